@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/shelldock/shelldock/internal/render"
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+// withSharedArgs returns the commands of a set with the shared arguments each
+// command uses added to its own argument definitions
+func withSharedArgs(cmdSet *repo.CommandSet) []repo.Command {
+	if len(cmdSet.Args) == 0 {
+		return cmdSet.Commands
+	}
+
+	commands := make([]repo.Command, len(cmdSet.Commands))
+	for i, cmd := range cmdSet.Commands {
+		cmd.Args = stepArgDefs(cmdSet.Args, cmd)
+		commands[i] = cmd
+	}
+	return commands
+}
+
+// stepArgDefs returns the argument definitions of a step: the shared arguments
+// it uses, overridden by the step's own definitions of the same name, then the
+// step's other arguments
+func stepArgDefs(shared []repo.ArgumentDef, cmd repo.Command) []repo.ArgumentDef {
+	own := make(map[string]repo.ArgumentDef)
+	for _, def := range cmd.Args {
+		own[def.Name] = def
+	}
+
+	var defs []repo.ArgumentDef
+	used := make(map[string]bool)
+	for _, def := range shared {
+		if stepDef, exists := own[def.Name]; exists {
+			defs = append(defs, def.Override(stepDef))
+			used[def.Name] = true
+		} else if usesArg(cmd, def.Name) {
+			defs = append(defs, def)
+			used[def.Name] = true
+		}
+	}
+	for _, def := range cmd.Args {
+		if !used[def.Name] {
+			defs = append(defs, def)
+			used[def.Name] = true
+		}
+	}
+
+	// Defaults computed from other shared arguments need those too
+	for added := true; added; {
+		added = false
+		for _, def := range shared {
+			if used[def.Name] {
+				continue
+			}
+			for _, d := range defs {
+				if defaultRefersTo(d, def.Name) {
+					defs = append(defs, def)
+					used[def.Name] = true
+					added = true
+					break
+				}
+			}
+		}
+	}
+	return defs
+}
+
+// templateActionPattern matches the actions of a template, e.g. {{name | upper}}
+var templateActionPattern = regexp.MustCompile(`\{\{(.*?)\}\}`)
+
+// templateStringPattern matches string literals inside template actions
+var templateStringPattern = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|` + "`[^`]*`")
+
+// usesArg reports whether a step refers to an argument in any of its commands,
+// checks, undo commands or its condition
+func usesArg(cmd repo.Command, name string) bool {
+	texts := []string{cmd.Command, cmd.Script, cmd.Check, cmd.Undo}
+	for _, platformCommands := range []map[string]string{cmd.Platforms, cmd.CheckPlatforms, cmd.UndoPlatforms} {
+		for _, text := range platformCommands {
+			texts = append(texts, text)
+		}
+	}
+	for _, text := range texts {
+		if refersTo(text, name) {
+			return true
+		}
+	}
+
+	if cmd.When != "" {
+		pattern := regexp.MustCompile(`\bargs\.` + regexp.QuoteMeta(name) + `\b`)
+		return pattern.MatchString(cmd.When)
+	}
+	return false
+}
+
+// refersTo reports whether a template refers to a value by name in any of its actions
+func refersTo(text, name string) bool {
+	reference := regexp.MustCompile(`(^|[^.$\w])` + regexp.QuoteMeta(name) + `\b`)
+	for _, action := range templateActionPattern.FindAllStringSubmatch(text, -1) {
+		if reference.MatchString(templateStringPattern.ReplaceAllString(action[1], "")) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultRefersTo reports whether an argument's default is computed from a value
+func defaultRefersTo(argDef repo.ArgumentDef, name string) bool {
+	return argDef.Name != name && (refersTo(argDef.Default, name) || refersTo(argDef.DefaultFrom, name))
+}
+
+// defaultOrder returns the positions of argument definitions so that every
+// argument comes after those its default is computed from. Defaults that
+// depend on each other are an error.
+func defaultOrder(defs []repo.ArgumentDef) ([]int, error) {
+	deps := make([][]int, len(defs))
+	for i, argDef := range defs {
+		for j, other := range defs {
+			if refersTo(argDef.Default, other.Name) || refersTo(argDef.DefaultFrom, other.Name) {
+				deps[i] = append(deps[i], j)
+			}
+		}
+	}
+
+	if cycle := findCycle(deps); cycle != nil {
+		names := make([]string, len(cycle))
+		for i, pos := range cycle {
+			names[i] = defs[pos].Name
+		}
+		return nil, fmt.Errorf("argument defaults depend on each other: %s", strings.Join(names, " -> "))
+	}
+	return dependencyOrder(deps), nil
+}
+
+// computeDefault returns the default of an argument whose default is computed.
+// A default_from snippet is run with the given shell and its trimmed output is
+// the default; if it fails or prints nothing, the default is used. Both can refer
+// to built-in facts and the values of other arguments, which are shell-quoted
+// in snippets.
+func computeDefault(argDef repo.ArgumentDef, shell shellSpec, facts, values map[string]string) (string, error) {
+	available := make(map[string]any, len(facts)+len(values))
+	for name, value := range facts {
+		available[name] = value
+	}
+	for name, value := range values {
+		available[name] = value
+	}
+
+	if argDef.DefaultFrom != "" {
+		snippet, err := render.Render(argDef.DefaultFrom, available, true)
+		if err != nil {
+			return "", err
+		}
+		var out bytes.Buffer
+		err = runInShell(shell, snippet, 0, nil, &out, os.Stderr)
+		if value := strings.TrimSpace(out.String()); err == nil && value != "" {
+			return value, nil
+		}
+		if argDef.Default == "" {
+			if err == nil {
+				err = fmt.Errorf("no output")
+			}
+			return "", fmt.Errorf("default_from %q failed: %w", argDef.DefaultFrom, err)
+		}
+	}
+	return render.Render(argDef.Default, available, false)
+}
+
+// staticDefault returns an argument's default, or "" if it is computed when the
+// run starts and taken from the resolved values instead
+func staticDefault(argDef repo.ArgumentDef) string {
+	if argDef.HasComputedDefault() {
+		return ""
+	}
+	return argDef.Default
+}
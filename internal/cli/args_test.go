@@ -0,0 +1,185 @@
+package cli
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+func TestWithSharedArgs(t *testing.T) {
+	cmdSet := &repo.CommandSet{
+		Args: []repo.ArgumentDef{
+			{Name: "size", Prompt: "Enter size", Default: "1G"},
+			{Name: "user", Required: true},
+		},
+		Commands: []repo.Command{
+			{Command: "fallocate -l {{size}} /swapfile"},
+			{Command: "echo {{size}}", Args: []repo.ArgumentDef{{Name: "size", Default: "2G"}}},
+			{Command: "free -h"},
+			{Command: "chown {{owner}} /swapfile", Args: []repo.ArgumentDef{{Name: "owner", Default: "root"}}},
+			{Command: "echo", When: "args.user != ''"},
+		},
+	}
+
+	commands := withSharedArgs(cmdSet)
+
+	if defs := commands[0].Args; len(defs) != 1 || defs[0].Default != "1G" {
+		t.Errorf("Expected the shared 'size', got %+v", defs)
+	}
+	if defs := commands[1].Args; len(defs) != 1 || defs[0].Default != "2G" || defs[0].Prompt != "Enter size" {
+		t.Errorf("Expected 'size' with the step's default, got %+v", defs)
+	}
+	if defs := commands[2].Args; len(defs) != 0 {
+		t.Errorf("Expected no args for a step using none, got %+v", defs)
+	}
+	if defs := commands[3].Args; len(defs) != 1 || defs[0].Name != "owner" {
+		t.Errorf("Expected only the step's own 'owner', got %+v", defs)
+	}
+	if defs := commands[4].Args; len(defs) != 1 || defs[0].Name != "user" {
+		t.Errorf("Expected 'user' from the condition, got %+v", defs)
+	}
+
+	// The set's own commands are left untouched
+	if len(cmdSet.Commands[0].Args) != 0 {
+		t.Errorf("Expected the set's commands to be unchanged, got %+v", cmdSet.Commands[0].Args)
+	}
+}
+
+func TestUsesArg(t *testing.T) {
+	tests := []struct {
+		cmd      repo.Command
+		name     string
+		expected bool
+	}{
+		{repo.Command{Command: "echo {{size}}"}, "size", true},
+		{repo.Command{Command: "echo {{sizes}}"}, "size", false},
+		{repo.Command{Platforms: map[string]string{"ubuntu": "echo {{size}}"}}, "size", true},
+		{repo.Command{Check: "test -f {{path}}"}, "path", true},
+		{repo.Command{Undo: "rm {{path}}"}, "path", true},
+		{repo.Command{When: "args.size == '2G'"}, "size", true},
+		{repo.Command{When: "args.sizes == '2G'"}, "size", false},
+		{repo.Command{Command: "echo size"}, "size", false},
+		{repo.Command{Command: "echo {{size | upper}}"}, "size", true},
+		{repo.Command{Command: "{{range tools}}{{.}} {{end}}"}, "tools", true},
+		{repo.Command{Command: `{{if eq mode "size"}}x{{end}}`}, "size", false},
+		{repo.Command{Command: "{{ .size }}"}, "size", false},
+	}
+
+	for _, tt := range tests {
+		if result := usesArg(tt.cmd, tt.name); result != tt.expected {
+			t.Errorf("usesArg(%+v, %q) = %v, expected %v", tt.cmd, tt.name, result, tt.expected)
+		}
+	}
+}
+
+func TestDefaultOrder(t *testing.T) {
+	defs := []repo.ArgumentDef{
+		{Name: "count", Default: "{{size | mib}}"},
+		{Name: "jobs", DefaultFrom: "nproc"},
+		{Name: "size", Default: "2G"},
+	}
+	order, err := defaultOrder(defs)
+	if err != nil {
+		t.Fatalf("defaultOrder failed: %v", err)
+	}
+	if len(order) != 3 || order[0] != 1 || order[1] != 2 || order[2] != 0 {
+		t.Errorf("Expected size before count, got %v", order)
+	}
+
+	defs = []repo.ArgumentDef{
+		{Name: "a", Default: "{{b}}"},
+		{Name: "b", DefaultFrom: "echo {{a}}"},
+	}
+	if _, err := defaultOrder(defs); err == nil || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Errorf("Expected a cycle error, got %v", err)
+	}
+	if _, err := defaultOrder([]repo.ArgumentDef{{Name: "a", Default: "{{a}}"}}); err == nil {
+		t.Error("Expected an error for a default referring to itself")
+	}
+}
+
+func TestComputeDefault(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell commands")
+	}
+
+	facts := map[string]string{"nproc": "4"}
+	values := map[string]string{"size": "2G", "name": "it's"}
+
+	tests := []struct {
+		def      repo.ArgumentDef
+		expected string
+		hasError bool
+	}{
+		{repo.ArgumentDef{Name: "count", Default: "{{size | mib}}"}, "2048", false},
+		{repo.ArgumentDef{Name: "jobs", Default: "{{nproc}}"}, "4", false},
+		{repo.ArgumentDef{Name: "jobs", DefaultFrom: "echo 8"}, "8", false},
+		{repo.ArgumentDef{Name: "greeting", DefaultFrom: "echo hi {{name}}"}, "hi it's", false},
+		{repo.ArgumentDef{Name: "jobs", DefaultFrom: "exit 1", Default: "1"}, "1", false},
+		{repo.ArgumentDef{Name: "jobs", DefaultFrom: "true", Default: "1"}, "1", false},
+		{repo.ArgumentDef{Name: "jobs", DefaultFrom: "exit 1"}, "", true},
+		{repo.ArgumentDef{Name: "count", Default: "{{missing}}"}, "", true},
+	}
+
+	for _, tt := range tests {
+		value, err := computeDefault(tt.def, shellSpec{}, facts, values)
+		if tt.hasError {
+			if err == nil {
+				t.Errorf("computeDefault(%+v) expected error, got %q", tt.def, value)
+			}
+			continue
+		}
+		if err != nil || value != tt.expected {
+			t.Errorf("computeDefault(%+v) = %q, %v, expected %q", tt.def, value, err, tt.expected)
+		}
+	}
+}
+
+func TestCollectCommandArgs_ComputedDefaults(t *testing.T) {
+	commands := []repo.Command{
+		{
+			Command: "dd bs=1M count={{count}}",
+			Args: []repo.ArgumentDef{
+				{Name: "count", Default: "{{size | mib}}"},
+				{Name: "size", Default: "2G"},
+			},
+		},
+	}
+
+	// Tests run without a terminal, so computed defaults are used as they are
+	values, err := collectCommandArgs(commands, map[string]string{}, nil)
+	if err != nil || values["count"] != "2048" {
+		t.Errorf("Expected count computed from the default size, got %v, %v", values, err)
+	}
+	values, err = collectCommandArgs(commands, map[string]string{"size": "4G"}, nil)
+	if err != nil || values["count"] != "4096" {
+		t.Errorf("Expected count computed from the given size, got %v, %v", values, err)
+	}
+	values, err = collectCommandArgs(commands, map[string]string{"size": "4G", "count": "10"}, nil)
+	if err != nil || values["count"] != "10" {
+		t.Errorf("Expected a given count to win, got %v, %v", values, err)
+	}
+
+	// Computed defaults are never used as they are written
+	if args := stepArgs(commands[0], map[string]string{}); args["count"] != "" || args["size"] != "2G" {
+		t.Errorf("Unexpected step args: %v", args)
+	}
+}
+
+func TestWithSharedArgs_ComputedDefaults(t *testing.T) {
+	cmdSet := &repo.CommandSet{
+		Args: []repo.ArgumentDef{
+			{Name: "size", Default: "2G"},
+			{Name: "count", Default: "{{size | mib}}"},
+		},
+		Commands: []repo.Command{{Command: "dd bs=1M count={{count}}"}},
+	}
+
+	// size is not used by the step itself, but count's default needs it
+	defs := withSharedArgs(cmdSet)[0].Args
+	if len(defs) != 2 || defs[0].Name != "count" || defs[1].Name != "size" {
+		t.Errorf("Expected count and size, got %+v", defs)
+	}
+}
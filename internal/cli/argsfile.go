@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/shelldock/shelldock/internal/repo"
+	"gopkg.in/yaml.v3"
+)
+
+// argEnvPrefix is the prefix of environment variables providing argument values
+const argEnvPrefix = "SHELLDOCK_ARG_"
+
+// argEnvNamePattern matches the characters of an argument name that can't be
+// part of an environment variable name
+var argEnvNamePattern = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// argEnvName returns the environment variable providing an argument, e.g.
+// SHELLDOCK_ARG_SIZE for size
+func argEnvName(name string) string {
+	return argEnvPrefix + strings.ToUpper(argEnvNamePattern.ReplaceAllString(name, "_"))
+}
+
+// envArgValues returns the values of the commands' arguments that are set
+// through SHELLDOCK_ARG_<NAME> environment variables
+func envArgValues(commands []repo.Command) map[string]string {
+	values := make(map[string]string)
+	for _, cmd := range commands {
+		for _, argDef := range cmd.Args {
+			if value, exists := os.LookupEnv(argEnvName(argDef.Name)); exists {
+				values[argDef.Name] = value
+			}
+		}
+	}
+	return values
+}
+
+// withEnvDefaults returns the commands with the default of each argument that
+// names an environment variable replaced by the variable's value, if it is set
+func withEnvDefaults(commands []repo.Command) []repo.Command {
+	result := make([]repo.Command, len(commands))
+	for i, cmd := range commands {
+		var defs []repo.ArgumentDef
+		for _, argDef := range cmd.Args {
+			if argDef.Env != "" {
+				if value := os.Getenv(argDef.Env); value != "" {
+					argDef.Default = value
+					argDef.DefaultFrom = ""
+				}
+			}
+			defs = append(defs, argDef)
+		}
+		cmd.Args = defs
+		result[i] = cmd
+	}
+	return result
+}
+
+// loadArgsFiles reads argument values from files, later files overriding earlier ones
+func loadArgsFiles(paths []string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, path := range paths {
+		fileValues, err := loadArgsFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range fileValues {
+			values[k] = v
+		}
+	}
+	return values, nil
+}
+
+// loadArgsFile reads argument values from a YAML (.yaml, .yml), JSON (.json)
+// or .env file, chosen by the file's extension
+func loadArgsFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read args file: %w", err)
+	}
+
+	var values map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var raw map[string]any
+		if err = yaml.Unmarshal(data, &raw); err == nil {
+			values, err = argFileValues(raw)
+		}
+	case ".json":
+		var raw map[string]any
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err = decoder.Decode(&raw); err == nil {
+			values, err = argFileValues(raw)
+		}
+	default:
+		values, err = parseDotEnv(string(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid args file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// argFileValues converts the values of a YAML or JSON args file to strings.
+// Lists become comma-separated values, as list arguments expect.
+func argFileValues(raw map[string]any) (map[string]string, error) {
+	values := make(map[string]string, len(raw))
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		switch value := raw[name].(type) {
+		case nil:
+			values[name] = ""
+		case []any:
+			items := make([]string, len(value))
+			for i, item := range value {
+				if _, nested := item.(map[string]any); nested {
+					return nil, fmt.Errorf("%s: list items must be plain values", name)
+				}
+				items[i] = fmt.Sprint(item)
+			}
+			values[name] = strings.Join(items, ",")
+		case map[string]any:
+			return nil, fmt.Errorf("%s: nested values are not supported", name)
+		default:
+			values[name] = fmt.Sprint(value)
+		}
+	}
+	return values, nil
+}
+
+// parseDotEnv parses KEY=VALUE lines. Blank lines, comments and an "export "
+// prefix are ignored, and values may be wrapped in single or double quotes;
+// double-quoted values support \n, \" and \\ escapes.
+func parseDotEnv(data string) (map[string]string, error) {
+	values := make(map[string]string)
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		eqIdx := strings.Index(line, "=")
+		if eqIdx <= 0 {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", i+1)
+		}
+		key := strings.TrimSpace(line[:eqIdx])
+		value := strings.TrimSpace(line[eqIdx+1:])
+
+		switch {
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			value = strings.NewReplacer(`\n`, "\n", `\"`, `"`, `\\`, `\`).Replace(value[1 : len(value)-1])
+		default:
+			// Unquoted values end at a comment
+			if idx := strings.Index(value, " #"); idx >= 0 {
+				value = strings.TrimSpace(value[:idx])
+			}
+		}
+		values[key] = value
+	}
+	return values, nil
+}
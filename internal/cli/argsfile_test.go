@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+func TestLoadArgsFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"vars.yaml": "size: 2G\ncount: 4\npackages:\n  - git\n  - curl\nnote: \"a, b\"\n",
+		"vars.json": `{"size": "2G", "count": 4, "packages": ["git", "curl"], "note": "a, b"}`,
+		".env":      "# swap\nsize=2G\nexport count=4\npackages=git,curl # tools\nnote=\"a, b\"\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+
+		values, err := loadArgsFile(path)
+		if err != nil {
+			t.Errorf("loadArgsFile(%s) failed: %v", name, err)
+			continue
+		}
+		expected := map[string]string{"size": "2G", "count": "4", "packages": "git,curl", "note": "a, b"}
+		if len(values) != len(expected) {
+			t.Errorf("loadArgsFile(%s) = %v, expected %v", name, values, expected)
+			continue
+		}
+		for k, v := range expected {
+			if values[k] != v {
+				t.Errorf("loadArgsFile(%s) key %q: got %q, expected %q", name, k, values[k], v)
+			}
+		}
+	}
+
+	// Later files override earlier ones
+	override := filepath.Join(dir, "override.env")
+	if err := os.WriteFile(override, []byte("size=4G\n"), 0644); err != nil {
+		t.Fatalf("Failed to write override: %v", err)
+	}
+	values, err := loadArgsFiles([]string{filepath.Join(dir, "vars.yaml"), override})
+	if err != nil || values["size"] != "4G" || values["count"] != "4" {
+		t.Errorf("Expected the later file to win, got %v, %v", values, err)
+	}
+
+	if _, err := loadArgsFile(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+	nested := filepath.Join(dir, "nested.yaml")
+	if err := os.WriteFile(nested, []byte("db:\n  host: localhost\n"), 0644); err != nil {
+		t.Fatalf("Failed to write nested: %v", err)
+	}
+	if _, err := loadArgsFile(nested); err == nil {
+		t.Error("Expected error for nested values")
+	}
+}
+
+func TestParseDotEnv(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected map[string]string
+		hasError bool
+	}{
+		{"name=John", map[string]string{"name": "John"}, false},
+		{"name='John Smith'", map[string]string{"name": "John Smith"}, false},
+		{`msg="line1\nline2"`, map[string]string{"msg": "line1\nline2"}, false},
+		{"url=http://x/#top", map[string]string{"url": "http://x/#top"}, false},
+		{"empty=", map[string]string{"empty": ""}, false},
+		{"\n# comment\n\n", map[string]string{}, false},
+		{"no-equals", nil, true},
+	}
+
+	for _, tt := range tests {
+		result, err := parseDotEnv(tt.input)
+		if tt.hasError {
+			if err == nil {
+				t.Errorf("parseDotEnv(%q) expected error, got nil", tt.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseDotEnv(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if len(result) != len(tt.expected) {
+			t.Errorf("parseDotEnv(%q) = %v, expected %v", tt.input, result, tt.expected)
+			continue
+		}
+		for k, v := range tt.expected {
+			if result[k] != v {
+				t.Errorf("parseDotEnv(%q) key %q: got %q, expected %q", tt.input, k, result[k], v)
+			}
+		}
+	}
+}
+
+func TestEnvArgValues(t *testing.T) {
+	t.Setenv("SHELLDOCK_ARG_SIZE", "2G")
+	t.Setenv("SHELLDOCK_ARG_DB_HOST", "db.local")
+	t.Setenv("SWAP_COUNT", "3")
+
+	commands := []repo.Command{
+		{Args: []repo.ArgumentDef{{Name: "size"}, {Name: "db-host"}, {Name: "user"}}},
+		{Args: []repo.ArgumentDef{{Name: "count", Default: "1", Env: "SWAP_COUNT"}, {Name: "mode", Default: "dev", Env: "SHELLDOCK_TEST_UNSET"}}},
+	}
+
+	values := envArgValues(commands)
+	if len(values) != 2 || values["size"] != "2G" || values["db-host"] != "db.local" {
+		t.Errorf("Unexpected values from the environment: %v", values)
+	}
+
+	defaulted := withEnvDefaults(commands)
+	if defs := defaulted[1].Args; defs[0].Default != "3" || defs[1].Default != "dev" {
+		t.Errorf("Expected the default from the environment, got %+v", defs)
+	}
+	if commands[1].Args[0].Default != "1" {
+		t.Error("Expected the original commands to be unchanged")
+	}
+}
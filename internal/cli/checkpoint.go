@@ -0,0 +1,289 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/shelldock/shelldock/internal/config"
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+const (
+	runsDirName        = "runs"
+	checkpointFileName = "state.json"
+)
+
+// runCheckpoint records the progress of a run so it can be resumed after a failure
+type runCheckpoint struct {
+	ID             string             `json:"id"`
+	Set            string             `json:"set"`
+	Version        string             `json:"version"`
+	Platform       string             `json:"platform"`
+	Local          bool               `json:"local,omitempty"`
+	SkipSteps      string             `json:"skip_steps,omitempty"`
+	OnlySteps      string             `json:"only_steps,omitempty"`
+	FromStep       string             `json:"from_step,omitempty"`
+	ToStep         string             `json:"to_step,omitempty"`
+	Args           map[string]string  `json:"args,omitempty"`
+	Vars           map[string]string  `json:"vars,omitempty"`
+	CompletedSteps []int              `json:"completed_steps,omitempty"`
+	Results        map[int]stepResult `json:"results,omitempty"`
+	FailedStep     int                `json:"failed_step,omitempty"`
+	StartedAt      time.Time          `json:"started_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+
+	secretArgs map[string]bool // Arguments whose values are only kept in memory
+}
+
+// Step result statuses, available to when expressions as steps.<n>.status
+const (
+	stepSuccess   = "success"
+	stepFailed    = "failed"
+	stepSkipped   = "skipped"
+	stepSatisfied = "satisfied"
+)
+
+// stepResult records the outcome of a step
+type stepResult struct {
+	Status     string `json:"status"`
+	ExitCode   int    `json:"exit_code"`
+	DurationMS int64  `json:"duration_ms,omitempty"`
+}
+
+// getRunsDir returns the directory holding run checkpoints (~/.shelldock/runs)
+func getRunsDir() (string, error) {
+	dir, err := config.GetShellDockDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, runsDirName), nil
+}
+
+// newRunCheckpoint creates a checkpoint with a unique run ID for the given command set
+func newRunCheckpoint(set, version, platform string) (*runCheckpoint, error) {
+	runsDir, err := getRunsDir()
+	if err != nil {
+		return nil, err
+	}
+	historyDir, err := getHistoryDir()
+	if err != nil {
+		return nil, err
+	}
+
+	// Run IDs are unique among resumable runs and the run history
+	now := time.Now()
+	baseID := fmt.Sprintf("%s-%s", set, now.Format("20060102-150405"))
+	id := baseID
+	for n := 2; ; n++ {
+		_, runErr := os.Stat(filepath.Join(runsDir, id))
+		_, historyErr := os.Stat(filepath.Join(historyDir, id+".json"))
+		if os.IsNotExist(runErr) && os.IsNotExist(historyErr) {
+			break
+		}
+		id = fmt.Sprintf("%s-%d", baseID, n)
+	}
+
+	return &runCheckpoint{
+		ID:        id,
+		Set:       set,
+		Version:   version,
+		Platform:  platform,
+		Args:      make(map[string]string),
+		Vars:      make(map[string]string),
+		StartedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// dir returns the directory of this run (~/.shelldock/runs/<run-id>)
+func (c *runCheckpoint) dir() (string, error) {
+	runsDir, err := getRunsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(runsDir, c.ID), nil
+}
+
+// setArgs records the resolved arguments of the run's commands, so a resumed
+// run doesn't prompt again. The values of secret arguments are not saved; a
+// resumed run resolves them again.
+func (c *runCheckpoint) setArgs(commands []repo.Command, values map[string]string) {
+	for _, cmd := range commands {
+		for _, argDef := range cmd.Args {
+			if argDef.IsSecret() {
+				if c.secretArgs == nil {
+					c.secretArgs = make(map[string]bool)
+				}
+				c.secretArgs[argDef.Name] = true
+			}
+		}
+	}
+	for name, value := range values {
+		c.Args[name] = value
+	}
+}
+
+// isCompleted reports whether the given step (1-indexed) already completed
+func (c *runCheckpoint) isCompleted(step int) bool {
+	for _, s := range c.CompletedSteps {
+		if s == step {
+			return true
+		}
+	}
+	return false
+}
+
+// markCompleted records a completed step and its result, and persists the checkpoint
+func (c *runCheckpoint) markCompleted(step int, result stepResult) error {
+	if !c.isCompleted(step) {
+		c.CompletedSteps = append(c.CompletedSteps, step)
+	}
+	c.setResult(step, result)
+	c.FailedStep = 0
+	return c.save()
+}
+
+// unmarkCompleted forgets a completed step after it was rolled back, and persists the checkpoint
+func (c *runCheckpoint) unmarkCompleted(step int) error {
+	for i, s := range c.CompletedSteps {
+		if s == step {
+			c.CompletedSteps = append(c.CompletedSteps[:i], c.CompletedSteps[i+1:]...)
+			break
+		}
+	}
+	delete(c.Results, step)
+	return c.save()
+}
+
+// markFailed records the failed step and persists the checkpoint
+func (c *runCheckpoint) markFailed(step int, result stepResult) error {
+	result.Status = stepFailed
+	c.setResult(step, result)
+	c.FailedStep = step
+	return c.save()
+}
+
+func (c *runCheckpoint) setResult(step int, result stepResult) {
+	if c.Results == nil {
+		c.Results = make(map[int]stepResult)
+	}
+	c.Results[step] = result
+}
+
+// save writes the checkpoint to ~/.shelldock/runs/<run-id>/state.json
+func (c *runCheckpoint) save() error {
+	dir, err := c.dir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create run directory: %w", err)
+	}
+
+	c.UpdatedAt = time.Now()
+	saved := *c
+	saved.Args = make(map[string]string, len(c.Args))
+	for name, value := range c.Args {
+		if !c.secretArgs[name] {
+			saved.Args[name] = value
+		}
+	}
+	data, err := json.MarshalIndent(&saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	// Arguments may hold sensitive values, keep the file private
+	if err := os.WriteFile(filepath.Join(dir, checkpointFileName), data, 0600); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// remove deletes the run directory once the run no longer needs to be resumed
+func (c *runCheckpoint) remove() error {
+	dir, err := c.dir()
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// loadCheckpoint loads the checkpoint of a run by its ID
+func loadCheckpoint(id string) (*runCheckpoint, error) {
+	runsDir, err := getRunsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(runsDir, id, checkpointFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("run '%s' not found", id)
+		}
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var c runCheckpoint
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	if c.Args == nil {
+		c.Args = make(map[string]string)
+	}
+	if c.Vars == nil {
+		c.Vars = make(map[string]string)
+	}
+	return &c, nil
+}
+
+// listCheckpoints returns all resumable runs, most recently updated first
+func listCheckpoints() ([]*runCheckpoint, error) {
+	runsDir, err := getRunsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(runsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*runCheckpoint{}, nil
+		}
+		return nil, fmt.Errorf("failed to read runs directory: %w", err)
+	}
+
+	var checkpoints []*runCheckpoint
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		c, err := loadCheckpoint(entry.Name())
+		if err != nil {
+			continue // Skip runs without a readable checkpoint
+		}
+		checkpoints = append(checkpoints, c)
+	}
+
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].UpdatedAt.After(checkpoints[j].UpdatedAt)
+	})
+	return checkpoints, nil
+}
+
+// findLatestCheckpoint returns the most recent resumable run of a command set
+func findLatestCheckpoint(set string) (*runCheckpoint, error) {
+	checkpoints, err := listCheckpoints()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range checkpoints {
+		if c.Set == set {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("no failed run found for command set '%s'", set)
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"os"
+	"testing"
+
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+func TestRunCheckpoint(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Temporarily override HOME
+	originalHome := os.Getenv("HOME")
+	defer func() {
+		if originalHome != "" {
+			_ = os.Setenv("HOME", originalHome)
+		}
+	}()
+
+	_ = os.Setenv("HOME", tmpDir)
+
+	checkpoint, err := newRunCheckpoint("docker", "v1", "ubuntu")
+	if err != nil {
+		t.Fatalf("newRunCheckpoint failed: %v", err)
+	}
+	commands := []repo.Command{{Args: []repo.ArgumentDef{{Name: "name"}, {Name: "token", Secret: true}}}}
+	checkpoint.setArgs(commands, map[string]string{"name": "John", "token": "s3cret"})
+
+	if err := checkpoint.markCompleted(1, stepResult{Status: stepSuccess}); err != nil {
+		t.Fatalf("markCompleted failed: %v", err)
+	}
+	if err := checkpoint.markFailed(2, stepResult{ExitCode: 3}); err != nil {
+		t.Fatalf("markFailed failed: %v", err)
+	}
+
+	loaded, err := findLatestCheckpoint("docker")
+	if err != nil {
+		t.Fatalf("findLatestCheckpoint failed: %v", err)
+	}
+	if loaded.ID != checkpoint.ID {
+		t.Errorf("Expected run ID %q, got %q", checkpoint.ID, loaded.ID)
+	}
+	if !loaded.isCompleted(1) || loaded.isCompleted(2) {
+		t.Errorf("Unexpected completed steps: %v", loaded.CompletedSteps)
+	}
+	if loaded.FailedStep != 2 {
+		t.Errorf("Expected failed step 2, got %d", loaded.FailedStep)
+	}
+	if result := loaded.Results[2]; result.Status != stepFailed || result.ExitCode != 3 {
+		t.Errorf("Unexpected result for step 2: %+v", result)
+	}
+	if loaded.Args["name"] != "John" {
+		t.Errorf("Expected arg name 'John', got %q", loaded.Args["name"])
+	}
+	if _, saved := loaded.Args["token"]; saved || checkpoint.Args["token"] != "s3cret" {
+		t.Errorf("Expected the secret kept in memory but not saved, got %q", loaded.Args["token"])
+	}
+
+	if _, err := findLatestCheckpoint("nginx"); err == nil {
+		t.Error("Expected error for command set without failed runs")
+	}
+
+	if err := loaded.remove(); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	if _, err := loadCheckpoint(checkpoint.ID); err == nil {
+		t.Error("Expected error loading removed checkpoint")
+	}
+}
@@ -32,6 +32,8 @@ var configShowCmd = &cobra.Command{
 		fmt.Println("ShellDock Configuration:")
 		fmt.Printf("  Platform setting: %s\n", cfg.Platform)
 		fmt.Printf("  Active platform: %s\n", platform)
+		shell := shellSpec{name: cfg.Shell, options: cfg.ShellOptions}
+		fmt.Printf("  Default shell: %s\n", shell)
 		fmt.Printf("  Config file: ~/.shelldock/.sdrc\n")
 	},
 }
@@ -57,9 +59,13 @@ Use "auto" to automatically detect the platform and distribution.`,
 			fmt.Fprintf(os.Stderr, "Detected distribution: %s\n", config.DetectLinuxDistribution())
 		}
 
-		cfg := &config.Config{
-			Platform: platform,
+		// Keep the other settings
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
 		}
+		cfg.Platform = platform
 
 		if err := config.SaveConfig(cfg); err != nil {
 			fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
@@ -79,8 +85,43 @@ Use "auto" to automatically detect the platform and distribution.`,
 	},
 }
 
+var configShellCmd = &cobra.Command{
+	Use:   "shell [shell] [option...]",
+	Short: "Set the default shell and shell options (e.g., bash pipefail)",
+	Long: `Set the shell that runs the commands of command sets that don't name one,
+and the options it is started with (e.g., errexit, pipefail).
+For example "shelldock config shell bash pipefail".
+Use "sh" without options to go back to the default.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		shell := shellSpec{name: args[0], options: args[1:]}
+		if err := validateShell(shell); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.Shell, cfg.ShellOptions = "", nil
+		if !shell.isDefault() {
+			cfg.Shell, cfg.ShellOptions = shell.name, shell.options
+		}
+
+		if err := config.SaveConfig(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving config: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Default shell set to: %s\n", shell)
+	},
+}
+
 func init() {
 	configCmd.AddCommand(configShowCmd)
 	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configShellCmd)
 }
 
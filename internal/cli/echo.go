@@ -15,6 +15,8 @@ var (
 	echoVersionFlag string
 	echoSkipFlag    string
 	echoOnlyFlag    string
+	echoFromFlag    string
+	echoToFlag      string
 )
 
 var echoCmd = &cobra.Command{
@@ -63,30 +65,58 @@ Examples:
 		}
 
 		// Filter commands if flags are provided
-		commandsToRun := cmdSet.Commands
+		commands := withShells(withSharedArgs(cmdSet), setShell(cmdSet))
+		commandsToRun := commands
+		indices := make([]int, len(commands))
+		for i := range commands {
+			indices[i] = i + 1
+		}
 
 		if echoSkipFlag != "" || echoOnlyFlag != "" {
-			var err error
-			var _ []int // originalIndices not needed for echo
-			commandsToRun, _, err = filterCommands(cmdSet.Commands, echoSkipFlag, echoOnlyFlag)
+			// Steps can also be selected by id or tag
+			skipNums, err := resolveStepIDs(cmdSet.Commands, echoSkipFlag)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				fmt.Fprintf(os.Stderr, "Error: invalid --skip format: %v\n", err)
+				os.Exit(1)
+			}
+			onlyNums, err := resolveStepIDs(cmdSet.Commands, echoOnlyFlag)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --only format: %v\n", err)
 				os.Exit(1)
 			}
 
-			if len(commandsToRun) == 0 {
-				fmt.Fprintf(os.Stderr, "Error: No commands to echo after filtering\n")
+			commandsToRun, indices, err = filterCommands(commands, skipNums, onlyNums)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
 		}
+		if echoFromFlag != "" || echoToFlag != "" {
+			first, last, err := resolveStepRange(cmdSet.Commands, echoFromFlag, echoToFlag)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			commandsToRun, _ = filterStepRange(commandsToRun, indices, first, last)
+		}
+		if len(commandsToRun) == 0 && len(commands) > 0 {
+			fmt.Fprintf(os.Stderr, "Error: No commands to echo after filtering\n")
+			os.Exit(1)
+		}
 
-		// Echo commands in plain format (one per line, no descriptions)
+		// Echo commands in plain format (one per line, no descriptions), with
+		// argument defaults filled in and other values left as placeholders
+		facts := builtinFacts(platform, "")
 		for _, cmd := range commandsToRun {
-			command := getCommandForPlatform(cmd, platform)
-			if command != "" {
-				fmt.Println(command)
+			if getCommandForPlatform(cmd, platform) == "" {
+				continue // Skip commands that don't have a command for this platform
+			}
+			rendered, err := renderStep(cmd, platform, showValues(commands, cmd, facts), quotesArgs(cmdSet))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s: %v\n", cmd.Description, err)
+				os.Exit(1)
 			}
-			// Skip commands that don't have a command for this platform
+			fmt.Println(rendered.command)
 		}
 	},
 }
@@ -95,8 +125,10 @@ func init() {
 	echoCmd.Flags().BoolVarP(&echoLocalFlag, "local", "l", false, "Only check local repository (skip bundled repository)")
 	echoCmd.Flags().StringVar(&echoVersionFlag, "ver", "", "Show specific version or tag (default: latest). Can also use name@version format")
 	echoCmd.Flags().StringVar(&echoVersionFlag, "version", "", "Show specific version or tag (default: latest) - alias for --ver")
-	echoCmd.Flags().StringVar(&echoSkipFlag, "skip", "", "Skip specific steps (e.g., --skip 1,2,3 or --skip 1-3)")
-	echoCmd.Flags().StringVar(&echoOnlyFlag, "only", "", "Run only specific steps (e.g., --only 1,2,3 or --only 1-3)")
+	echoCmd.Flags().StringVar(&echoSkipFlag, "skip", "", "Skip specific steps by number, range, id or tag (e.g., --skip 1-3 or --skip tag:verify)")
+	echoCmd.Flags().StringVar(&echoOnlyFlag, "only", "", "Run only specific steps by number, range, id or tag (e.g., --only 1-3 or --only tag:verify)")
+	echoCmd.Flags().StringVar(&echoFromFlag, "from", "", "Start at a step, given by number or id")
+	echoCmd.Flags().StringVar(&echoToFlag, "to", "", "Stop after a step, given by number or id")
 }
 
 
@@ -0,0 +1,323 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+// planMarkerPattern matches the line starting a step in a plan edited with --edit
+var planMarkerPattern = regexp.MustCompile(`^# --- step ([0-9]+)\b`)
+
+// pendingPattern matches the placeholders of values that are only known once
+// the run starts, as they appear in a rendered command
+var pendingPattern = regexp.MustCompile(`^\{\{([a-zA-Z_][a-zA-Z0-9_]*)\}\}`)
+
+// planStep is a step of a plan edited with --edit
+type planStep struct {
+	num         int
+	description string
+	command     string // Rendered command, with placeholders for pending values
+}
+
+// formatPlan writes the steps of a run as a script to edit. Each step starts
+// with a marker line naming its number and description.
+func formatPlan(cmdSet *repo.CommandSet, platform string, steps []planStep) string {
+	var b strings.Builder
+	name := cmdSet.Name
+	if cmdSet.Version != "" {
+		name += "@" + cmdSet.Version
+	}
+	fmt.Fprintf(&b, "# ShellDock plan: %s on %s\n", name, platform)
+	b.WriteString("#\n")
+	b.WriteString("# Edit the commands below, then save and quit to continue. Delete the\n")
+	b.WriteString("# commands of a step to skip it, or empty the file to cancel the run.\n")
+	b.WriteString("# Keep the \"# --- step\" lines, they tell the steps apart. Comments\n")
+	b.WriteString("# right below them are ignored.\n")
+	for _, step := range steps {
+		fmt.Fprintf(&b, "\n# --- step %d: %s\n%s\n", step.num, step.description, step.command)
+	}
+	return b.String()
+}
+
+// parsePlan reads an edited plan and returns the command of each step that is
+// still in it. Comment lines right after a step's marker and blank lines
+// around its command are not part of the command.
+func parsePlan(text string, steps []planStep) (map[int]string, error) {
+	known := make(map[int]bool, len(steps))
+	for _, step := range steps {
+		known[step.num] = true
+	}
+
+	commands := make(map[int]string)
+	current := 0
+	var lines []string
+	flush := func() {
+		if current == 0 {
+			return
+		}
+		for len(lines) > 0 && (strings.TrimSpace(lines[0]) == "" || strings.HasPrefix(strings.TrimSpace(lines[0]), "#")) {
+			lines = lines[1:]
+		}
+		if command := strings.TrimRight(strings.Join(lines, "\n"), " \t\r\n"); command != "" {
+			commands[current] = command
+		}
+	}
+
+	for i, line := range strings.Split(text, "\n") {
+		if match := planMarkerPattern.FindStringSubmatch(line); match != nil {
+			flush()
+			num, _ := strconv.Atoi(match[1])
+			if !known[num] {
+				return nil, fmt.Errorf("line %d: step %d is not part of this run", i+1, num)
+			}
+			if _, seen := commands[num]; seen {
+				return nil, fmt.Errorf("line %d: step %d appears more than once", i+1, num)
+			}
+			current, lines = num, nil
+			continue
+		}
+		if current == 0 {
+			if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+				return nil, fmt.Errorf("line %d: commands must follow a \"# --- step\" line", i+1)
+			}
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return commands, nil
+}
+
+// planTemplate turns an edited command back into a template. Placeholders for
+// pending values stay placeholders; any other {{ is literal text.
+func planTemplate(command string, pending map[string]bool) string {
+	var b strings.Builder
+	for {
+		idx := strings.Index(command, "{{")
+		if idx < 0 {
+			b.WriteString(command)
+			return b.String()
+		}
+		b.WriteString(command[:idx])
+		command = command[idx:]
+		if match := pendingPattern.FindStringSubmatch(command); match != nil && pending[match[1]] {
+			b.WriteString(match[0])
+			command = command[len(match[0]):]
+			continue
+		}
+		b.WriteString(`{{"{{"}}`)
+		command = command[2:]
+	}
+}
+
+// pendingNames returns the names of the values that are only known once the
+// run starts: the run's directory and the output that steps register
+func pendingNames(commands []repo.Command) map[string]bool {
+	pending := map[string]bool{runDirFact: true}
+	for _, cmd := range commands {
+		if cmd.Register != "" {
+			pending[cmd.Register] = true
+		}
+	}
+	return pending
+}
+
+// editPlan opens a plan in the editor and returns the command of each step
+// left in it. A plan that can't be read can be edited again. It returns nil if
+// the plan was emptied or the user gave up.
+func editPlan(text string, steps []planStep) (map[int]string, error) {
+	file, err := os.CreateTemp("", "shelldock-plan-*.sh")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create plan file: %w", err)
+	}
+	path := file.Name()
+	defer os.Remove(path)
+
+	_, err = file.WriteString(text)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write plan file: %w", err)
+	}
+
+	for {
+		if err := openEditor(path); err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read plan file: %w", err)
+		}
+		if strings.TrimSpace(string(data)) == "" {
+			return nil, nil
+		}
+
+		commands, err := parsePlan(string(data), steps)
+		if err == nil {
+			return commands, nil
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  Invalid plan: %v\n", err)
+		if !confirm("Edit the plan again?") {
+			return nil, nil
+		}
+	}
+}
+
+// editedCommandSet returns a command set with the commands of edited steps
+// replaced and removed steps left out, to be saved as a new version. Edited
+// commands refer to secrets by argument again rather than by environment
+// variable. Other values are part of the edited commands, so edited steps drop
+// the arguments they no longer refer to.
+func editedCommandSet(cmdSet *repo.CommandSet, edits map[int]string, removed map[int]bool) (*repo.CommandSet, error) {
+	secrets := make(map[string]bool)
+	for _, argDef := range cmdSet.Args {
+		if argDef.IsSecret() {
+			secrets[argDef.Name] = true
+		}
+	}
+
+	edited := *cmdSet
+	edited.Commands = nil
+	for i, cmd := range cmdSet.Commands {
+		num := i + 1
+		if removed[num] {
+			continue
+		}
+		if command, exists := edits[num]; exists {
+			for _, argDef := range cmd.Args {
+				if argDef.IsSecret() {
+					secrets[argDef.Name] = true
+				}
+			}
+			names := make([]string, 0, len(secrets))
+			for name := range secrets {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				command = strings.ReplaceAll(command, `"${`+secretEnvName(name)+`}"`, "{{"+name+"}}")
+			}
+			setEditedCommand(&cmd, command)
+			cmd.Args = usedArgDefs(cmd)
+		}
+		edited.Commands = append(edited.Commands, cmd)
+	}
+
+	// Steps may have depended on a removed step
+	if _, err := resolveDependencies(edited.Commands); err != nil {
+		return nil, err
+	}
+	return &edited, nil
+}
+
+// setEditedCommand replaces the command of a step with one edited in a plan. A
+// script edited within its here document stays a script.
+func setEditedCommand(cmd *repo.Command, command string) {
+	if script, ok := unwrapScript(*cmd, command); ok {
+		cmd.Script = script
+		return
+	}
+	cmd.Command, cmd.Platforms = command, nil
+	cmd.Script, cmd.Interpreter = "", ""
+}
+
+// usedArgDefs returns the argument definitions of a step that it still refers
+// to, with those their defaults are computed from. Secrets are kept, since
+// commands can also refer to them by environment variable.
+func usedArgDefs(cmd repo.Command) []repo.ArgumentDef {
+	keep := make(map[string]bool)
+	for _, argDef := range cmd.Args {
+		keep[argDef.Name] = argDef.IsSecret() || usesArg(cmd, argDef.Name)
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, argDef := range cmd.Args {
+			if !keep[argDef.Name] {
+				continue
+			}
+			for _, other := range cmd.Args {
+				if !keep[other.Name] && (refersTo(argDef.Default, other.Name) || refersTo(argDef.DefaultFrom, other.Name)) {
+					keep[other.Name], changed = true, true
+				}
+			}
+		}
+	}
+
+	var defs []repo.ArgumentDef
+	for _, argDef := range cmd.Args {
+		if keep[argDef.Name] {
+			defs = append(defs, argDef)
+		}
+	}
+	return defs
+}
+
+// nextVersion returns the version after the highest numbered one, e.g. v3
+// after v1 and v2. Versions may be listed with a tag or "(latest)".
+func nextVersion(versions ...string) string {
+	highest := 0
+	for _, version := range versions {
+		fields := strings.Fields(version)
+		if len(fields) == 0 {
+			continue
+		}
+		if num, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(fields[0]), "v")); err == nil && num > highest {
+			highest = num
+		}
+	}
+	return fmt.Sprintf("v%d", highest+1)
+}
+
+// editRunPlan opens the rendered steps of a run in the editor. It returns the
+// template of each step whose command changed and the steps that were removed,
+// or ok false if the run was cancelled.
+func editRunPlan(cmdSet *repo.CommandSet, platform string, steps []planStep) (edits map[int]string, removed map[int]bool, ok bool, err error) {
+	text := formatPlan(cmdSet, platform, steps)
+	commands, err := editPlan(text, steps)
+	if err != nil || commands == nil {
+		return nil, nil, false, err
+	}
+
+	// Compare with the plan as it was written, which reads back the same way
+	unchanged, _ := parsePlan(text, steps)
+	pending := pendingNames(cmdSet.Commands)
+	edits = make(map[int]string)
+	removed = make(map[int]bool)
+	for _, step := range steps {
+		command, exists := commands[step.num]
+		if !exists {
+			removed[step.num] = true
+		} else if command != unchanged[step.num] {
+			edits[step.num] = planTemplate(command, pending)
+		}
+	}
+	return edits, removed, true, nil
+}
+
+// saveEditedPlan saves a command set with the edits of a plan as a new version
+// in the local repository, reporting the outcome without stopping the run
+func saveEditedPlan(cmdSet *repo.CommandSet, edits map[int]string, removed map[int]bool) {
+	edited, err := editedCommandSet(cmdSet, edits, removed)
+	if err == nil {
+		var manager *repo.Manager
+		if manager, err = repo.NewManager(); err == nil {
+			local := manager.GetLocalRepo()
+			// Sets without a version are saved as v1
+			versions, _ := local.ListVersions(cmdSet.Name)
+			edited.Version = nextVersion(append(versions, "v1", cmdSet.Version)...)
+			err = local.SaveCommandSet(edited, edited.Version)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to save the edited plan: %v\n", err)
+		return
+	}
+	fmt.Printf("💾 Saved as local version %s, run it again with: shelldock %s@%s\n", edited.Version, edited.Name, edited.Version)
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+func TestParsePlan(t *testing.T) {
+	cmdSet := &repo.CommandSet{Name: "swap", Version: "v1"}
+	steps := []planStep{
+		{num: 1, description: "Create swap file", command: "sudo fallocate -l 2G /swapfile"},
+		{num: 3, description: "Enable swap", command: "sudo mkswap /swapfile\nsudo swapon /swapfile\n"},
+	}
+
+	// A plan reads back as it was written
+	commands, err := parsePlan(formatPlan(cmdSet, "ubuntu", steps), steps)
+	if err != nil {
+		t.Fatalf("parsePlan failed: %v", err)
+	}
+	if len(commands) != 2 || commands[1] != steps[0].command || commands[3] != "sudo mkswap /swapfile\nsudo swapon /swapfile" {
+		t.Errorf("Unexpected commands: %q", commands)
+	}
+
+	edited := "# header\n\n# --- step 1: Create swap file\n# note\nsudo fallocate -l 4G /swapfile\n\n# --- step 3: Enable swap\n\n"
+	commands, err = parsePlan(edited, steps)
+	if err != nil {
+		t.Fatalf("parsePlan failed: %v", err)
+	}
+	if len(commands) != 1 || commands[1] != "sudo fallocate -l 4G /swapfile" {
+		t.Errorf("Expected step 3 to be removed, got %q", commands)
+	}
+
+	invalid := []string{
+		"echo before any step\n# --- step 1: x\necho hi\n",
+		"# --- step 2: unknown\necho hi\n",
+		"# --- step 1: x\necho hi\n# --- step 1: x\necho again\n",
+	}
+	for _, text := range invalid {
+		if _, err := parsePlan(text, steps); err == nil {
+			t.Errorf("parsePlan(%q) expected error", text)
+		}
+	}
+}
+
+func TestPlanTemplate(t *testing.T) {
+	pending := map[string]bool{"out": true, runDirFact: true}
+
+	tests := []struct {
+		command  string
+		expected string
+	}{
+		{"echo hi", "echo hi"},
+		{"echo {{out}} > {{shelldock_run_dir}}/x", "echo {{out}} > {{shelldock_run_dir}}/x"},
+		{"docker ps --format {{.Names}}", `docker ps --format {{"{{"}}.Names}}`},
+		{"echo {{size}}", `echo {{"{{"}}size}}`},
+	}
+
+	for _, tt := range tests {
+		if result := planTemplate(tt.command, pending); result != tt.expected {
+			t.Errorf("planTemplate(%q) = %q, expected %q", tt.command, result, tt.expected)
+		}
+	}
+}
+
+func TestEditedCommandSet(t *testing.T) {
+	cmdSet := &repo.CommandSet{
+		Name: "deploy",
+		Args: []repo.ArgumentDef{{Name: "token", Secret: true}},
+		Commands: []repo.Command{
+			{ID: "build", Description: "Build", Command: "make"},
+			{Description: "Push", Platforms: map[string]string{"ubuntu": "push --token {{token}}"}},
+			{Description: "Notify", Command: "notify"},
+			{Description: "Resize", Command: "resize {{count}} {{host}}", Args: []repo.ArgumentDef{
+				{Name: "size"}, {Name: "count", Default: "{{size | mib}}"}, {Name: "host"}, {Name: "key", Secret: true},
+			}},
+		},
+	}
+
+	edits := map[int]string{2: `push --force --token "${SHELLDOCK_SECRET_TOKEN}"`, 4: "resize {{count}} web1"}
+	edited, err := editedCommandSet(cmdSet, edits, map[int]bool{3: true})
+	if err != nil {
+		t.Fatalf("editedCommandSet failed: %v", err)
+	}
+	if len(edited.Commands) != 3 || edited.Commands[0].Command != "make" {
+		t.Fatalf("Unexpected commands: %+v", edited.Commands)
+	}
+	if push := edited.Commands[1]; push.Command != "push --force --token {{token}}" || push.Platforms != nil {
+		t.Errorf("Unexpected edited step: %+v", push)
+	}
+	// The edited step keeps the arguments it still refers to, those their
+	// defaults need and secrets
+	var names []string
+	for _, argDef := range edited.Commands[2].Args {
+		names = append(names, argDef.Name)
+	}
+	if strings.Join(names, ",") != "size,count,key" {
+		t.Errorf("Expected the arguments size, count and key to be kept, got %v", names)
+	}
+	if len(cmdSet.Commands) != 4 || cmdSet.Commands[1].Platforms == nil || len(cmdSet.Commands[3].Args) != 4 {
+		t.Error("Expected the original command set to be unchanged")
+	}
+
+	// Removing a step others depend on is an error
+	cmdSet.Commands[2].DependsOn = []string{"build"}
+	if _, err := editedCommandSet(cmdSet, nil, map[int]bool{1: true}); err == nil {
+		t.Error("Expected an error for a dependency on a removed step")
+	}
+}
+
+func TestNextVersion(t *testing.T) {
+	tests := []struct {
+		versions []string
+		expected string
+	}{
+		{nil, "v1"},
+		{[]string{"v1"}, "v2"},
+		{[]string{"v1", "v3 [nginx]", "v2 (latest)"}, "v4"},
+		{[]string{"2"}, "v3"},
+		{[]string{"beta", ""}, "v1"},
+	}
+
+	for _, tt := range tests {
+		if result := nextVersion(tt.versions...); result != tt.expected {
+			t.Errorf("nextVersion(%v) = %q, expected %q", tt.versions, result, tt.expected)
+		}
+	}
+}
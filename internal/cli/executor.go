@@ -0,0 +1,213 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+const (
+	defaultRetryDelay = 5 * time.Second
+	maxRetryDelay     = 10 * time.Minute
+)
+
+// retryPolicy describes how long a step may run and how it is retried
+type retryPolicy struct {
+	timeout     time.Duration
+	retries     int
+	delay       time.Duration
+	exponential bool
+}
+
+// parseRetryPolicy reads the timeout and retry settings of a command
+func parseRetryPolicy(cmd repo.Command) (retryPolicy, error) {
+	policy := retryPolicy{
+		retries: cmd.Retries,
+		delay:   defaultRetryDelay,
+	}
+
+	if cmd.Timeout != "" {
+		timeout, err := time.ParseDuration(cmd.Timeout)
+		if err != nil || timeout <= 0 {
+			return policy, fmt.Errorf("invalid timeout '%s' (use a duration like 30s or 5m)", cmd.Timeout)
+		}
+		policy.timeout = timeout
+	}
+
+	if cmd.Retries < 0 {
+		return policy, fmt.Errorf("retries must be >= 0, got: %d", cmd.Retries)
+	}
+
+	if cmd.RetryDelay != "" {
+		delay, err := time.ParseDuration(cmd.RetryDelay)
+		if err != nil || delay < 0 {
+			return policy, fmt.Errorf("invalid retry_delay '%s' (use a duration like 10s)", cmd.RetryDelay)
+		}
+		policy.delay = delay
+	}
+
+	switch strings.ToLower(cmd.RetryBackoff) {
+	case "", "fixed":
+	case "exponential":
+		policy.exponential = true
+	default:
+		return policy, fmt.Errorf("invalid retry_backoff '%s' (use fixed or exponential)", cmd.RetryBackoff)
+	}
+
+	return policy, nil
+}
+
+// delayBefore returns the delay before the given retry (1-indexed)
+func (p retryPolicy) delayBefore(retry int) time.Duration {
+	delay := p.delay
+	if p.exponential {
+		for i := 1; i < retry && delay < maxRetryDelay; i++ {
+			delay *= 2
+		}
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+	return delay
+}
+
+// String describes the policy for command previews, empty when nothing is configured
+func (p retryPolicy) String() string {
+	var parts []string
+	if p.timeout > 0 {
+		parts = append(parts, fmt.Sprintf("timeout %s", p.timeout))
+	}
+	if p.retries > 0 {
+		retries := fmt.Sprintf("%d retries, %s apart", p.retries, p.delay)
+		if p.exponential {
+			retries = fmt.Sprintf("%d retries, %s apart with exponential backoff", p.retries, p.delay)
+		}
+		parts = append(parts, retries)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// stepStreams are the streams a step's commands run with
+type stepStreams struct {
+	stdin  io.Reader // nil runs the command without input
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// terminalStreams attach commands to the terminal
+var terminalStreams = stepStreams{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+
+// commandRunner executes the commands of a run
+type commandRunner interface {
+	// run executes one attempt of a command with the given streams
+	run(command string, timeout time.Duration, streams stepStreams) error
+	// check runs a check command without output and reports whether it exited 0
+	check(command string, timeout time.Duration) bool
+	// withShell returns a runner that runs commands in the given shell
+	withShell(shell shellSpec) commandRunner
+}
+
+// processRunner runs every command in a fresh shell process, sh -c by default
+type processRunner struct {
+	shell shellSpec
+}
+
+func (p processRunner) run(command string, timeout time.Duration, streams stepStreams) error {
+	return runInShell(p.shell, command, timeout, streams.stdin, streams.stdout, streams.stderr)
+}
+
+func (p processRunner) check(command string, timeout time.Duration) bool {
+	return runInShell(p.shell, command, timeout, nil, io.Discard, io.Discard) == nil
+}
+
+func (p processRunner) withShell(shell shellSpec) commandRunner {
+	return processRunner{shell: shell}
+}
+
+// runWithRetries runs a command, retrying failed attempts according to the policy.
+// If capture is set, it receives the stdout of the last attempt while output is still streamed.
+// onRetry, if set, is called before each retry.
+func runWithRetries(runner commandRunner, command string, policy retryPolicy, streams stepStreams, capture *bytes.Buffer, onRetry func(attempt, attempts int, err error, delay time.Duration)) error {
+	for attempt := 1; ; attempt++ {
+		attemptStreams := streams
+		if capture != nil {
+			capture.Reset()
+			attemptStreams.stdout = io.MultiWriter(streams.stdout, capture)
+		}
+
+		err := runner.run(command, policy.timeout, attemptStreams)
+		if err == nil {
+			return nil
+		}
+		if attempt > policy.retries {
+			return err
+		}
+
+		delay := policy.delayBefore(attempt)
+		if onRetry != nil {
+			onRetry(attempt, policy.retries+1, err, delay)
+		}
+		time.Sleep(delay)
+	}
+}
+
+// exitCodeOf returns the exit code of a finished command, or -1 if it did not exit normally
+func exitCodeOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// runInShell runs a command with the -c option of a shell. When a timeout is
+// set, the command runs in its own process group, which is killed as a whole
+// once the timeout expires and receives the SIGINT and SIGTERM sent to
+// shelldock. If it reads from the terminal, the group gets the terminal while
+// it runs.
+func runInShell(shell shellSpec, command string, timeout time.Duration, stdin io.Reader, stdout, stderr io.Writer) error {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	args := shell.command(command)
+	execCmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	execCmd.Stdin = stdin
+	execCmd.Stdout = stdout
+	execCmd.Stderr = stderr
+
+	if timeout == 0 {
+		return execCmd.Run()
+	}
+
+	tty := setProcessGroup(execCmd)
+	execCmd.Cancel = func() error {
+		return killProcessGroup(execCmd)
+	}
+	if err := execCmd.Start(); err != nil {
+		return err
+	}
+	stop := forwardSignals(execCmd)
+	err := execCmd.Wait()
+	stop()
+	restoreTerminal(tty)
+
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+func TestParseRetryPolicy(t *testing.T) {
+	policy, err := parseRetryPolicy(repo.Command{
+		Timeout:      "5m",
+		Retries:      3,
+		RetryDelay:   "2s",
+		RetryBackoff: "exponential",
+	})
+	if err != nil {
+		t.Fatalf("parseRetryPolicy failed: %v", err)
+	}
+	if policy.timeout != 5*time.Minute {
+		t.Errorf("Expected timeout 5m, got %s", policy.timeout)
+	}
+	if policy.retries != 3 || !policy.exponential {
+		t.Errorf("Unexpected policy: %+v", policy)
+	}
+
+	expectedDelays := []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second}
+	for i, expected := range expectedDelays {
+		if delay := policy.delayBefore(i + 1); delay != expected {
+			t.Errorf("delayBefore(%d) = %s, expected %s", i+1, delay, expected)
+		}
+	}
+
+	// Defaults
+	policy, err = parseRetryPolicy(repo.Command{Retries: 2})
+	if err != nil {
+		t.Fatalf("parseRetryPolicy failed: %v", err)
+	}
+	if policy.timeout != 0 || policy.delayBefore(2) != defaultRetryDelay {
+		t.Errorf("Unexpected default policy: %+v", policy)
+	}
+
+	invalid := []repo.Command{
+		{Timeout: "soon"},
+		{Timeout: "-5s"},
+		{Retries: -1},
+		{RetryDelay: "later"},
+		{RetryBackoff: "linear"},
+	}
+	for _, cmd := range invalid {
+		if _, err := parseRetryPolicy(cmd); err == nil {
+			t.Errorf("parseRetryPolicy(%+v) expected error, got nil", cmd)
+		}
+	}
+}
+
+func TestRunInShellTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("process groups are not supported on Windows")
+	}
+
+	start := time.Now()
+	err := runInShell(shellSpec{}, "sleep 5 & sleep 5; wait", 200*time.Millisecond, nil, io.Discard, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Command was not killed on timeout (took %s)", elapsed)
+	}
+
+	if err := runInShell(shellSpec{}, "true", time.Second, nil, io.Discard, io.Discard); err != nil {
+		t.Errorf("Expected success, got %v", err)
+	}
+}
+
+func TestRunWithRetries(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell commands")
+	}
+
+	marker := t.TempDir() + "/attempted"
+	// Fails on the first attempt, succeeds on the second
+	command := "if [ -f " + marker + " ]; then exit 0; fi; touch " + marker + "; exit 1"
+	if err := runWithRetries(processRunner{}, command, retryPolicy{retries: 1}, terminalStreams, nil, nil); err != nil {
+		t.Errorf("Expected success after retry, got %v", err)
+	}
+
+	if err := runWithRetries(processRunner{}, "exit 1", retryPolicy{retries: 2}, terminalStreams, nil, nil); err == nil {
+		t.Error("Expected error after exhausting retries")
+	}
+}
+
+func TestRunWithRetriesCapture(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell commands")
+	}
+
+	var capture bytes.Buffer
+	if err := runWithRetries(processRunner{}, "echo '  v1.2.3  '", retryPolicy{}, terminalStreams, &capture, nil); err != nil {
+		t.Fatalf("runWithRetries failed: %v", err)
+	}
+	if got := strings.TrimSpace(capture.String()); got != "v1.2.3" {
+		t.Errorf("Expected captured output 'v1.2.3', got %q", got)
+	}
+}
@@ -0,0 +1,347 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+// defaultJobs is the number of steps run at the same time when steps declare dependencies
+const defaultJobs = 4
+
+// stepIDPattern matches valid step ids. Ids start with a letter so they can't be confused with step numbers.
+var stepIDPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// hasDependencies reports whether any step declares depends_on, which makes the
+// command set a dependency graph instead of a sequence
+func hasDependencies(commands []repo.Command) bool {
+	for _, cmd := range commands {
+		if len(cmd.DependsOn) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveDependencies validates step ids and returns the dependencies of each
+// step as 0-based indices. Dependencies refer to a step id or a step number.
+func resolveDependencies(commands []repo.Command) ([][]int, error) {
+	ids := make(map[string]int)
+	for i, cmd := range commands {
+		if cmd.ID == "" {
+			continue
+		}
+		if !stepIDPattern.MatchString(cmd.ID) {
+			return nil, fmt.Errorf("step %d: invalid id '%s' (use letters, digits, '-' and '_', starting with a letter)", i+1, cmd.ID)
+		}
+		if other, exists := ids[cmd.ID]; exists {
+			return nil, fmt.Errorf("step %d: id '%s' is already used by step %d", i+1, cmd.ID, other+1)
+		}
+		ids[cmd.ID] = i
+	}
+
+	deps := make([][]int, len(commands))
+	for i, cmd := range commands {
+		for _, ref := range cmd.DependsOn {
+			ref = strings.TrimSpace(ref)
+			dep, exists := ids[ref]
+			if !exists {
+				num, err := strconv.Atoi(ref)
+				if err != nil || num < 1 || num > len(commands) {
+					return nil, fmt.Errorf("step %d: depends on unknown step '%s'", i+1, ref)
+				}
+				dep = num - 1
+			}
+			if dep == i {
+				return nil, fmt.Errorf("step %d: cannot depend on itself", i+1)
+			}
+			deps[i] = append(deps[i], dep)
+		}
+	}
+
+	if cycle := findCycle(deps); cycle != nil {
+		steps := make([]string, len(cycle))
+		for i, step := range cycle {
+			steps[i] = stepLabel(commands[step], step+1)
+		}
+		return nil, fmt.Errorf("dependency cycle: %s", strings.Join(steps, " -> "))
+	}
+
+	return deps, nil
+}
+
+// findCycle returns the steps of a dependency cycle, or nil if there is none
+func findCycle(deps [][]int) []int {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(deps))
+	var path []int
+
+	var visit func(step int) []int
+	visit = func(step int) []int {
+		state[step] = visiting
+		path = append(path, step)
+		for _, dep := range deps[step] {
+			switch state[dep] {
+			case visiting:
+				for i, s := range path {
+					if s == dep {
+						return append(append([]int{}, path[i:]...), dep)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[step] = visited
+		return nil
+	}
+
+	for step := range deps {
+		if state[step] == unvisited {
+			if cycle := visit(step); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// dependencyOrder returns the positions of steps so that every step comes after
+// its dependencies, keeping the original order wherever possible
+func dependencyOrder(deps [][]int) []int {
+	done := make([]bool, len(deps))
+	order := make([]int, 0, len(deps))
+	for len(order) < len(deps) {
+		progressed := false
+		for step := range deps {
+			if done[step] || !dependenciesDone(deps[step], done) {
+				continue
+			}
+			done[step] = true
+			order = append(order, step)
+			progressed = true
+			break // Restart so earlier steps that became ready go first
+		}
+		if !progressed {
+			break // Cycle, rejected by resolveDependencies
+		}
+	}
+	return order
+}
+
+func dependenciesDone(deps []int, done []bool) bool {
+	for _, dep := range deps {
+		if !done[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveStepIDs replaces step ids and tags (tag:name) in a --skip/--only
+// selection with their step numbers, and completes open-ended ranges: "3-"
+// ends at the last step and "-3" starts at the first
+func resolveStepIDs(commands []repo.Command, selection string) (string, error) {
+	if selection == "" {
+		return "", nil
+	}
+
+	parts := strings.Split(selection, ",")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "":
+		case strings.HasPrefix(part, "tag:"):
+			tag := strings.TrimSpace(strings.TrimPrefix(part, "tag:"))
+			var nums []string
+			for j, cmd := range commands {
+				if hasTag(cmd, tag) {
+					nums = append(nums, strconv.Itoa(j+1))
+				}
+			}
+			if len(nums) == 0 {
+				return "", fmt.Errorf("no step has tag '%s'", tag)
+			}
+			parts[i] = strings.Join(nums, ",")
+		case strings.HasSuffix(part, "-") && isStepNumber(strings.TrimSuffix(part, "-")):
+			parts[i] = part + strconv.Itoa(len(commands))
+		case strings.HasPrefix(part, "-") && isStepNumber(strings.TrimPrefix(part, "-")):
+			parts[i] = "1" + part
+		case part[0] >= '0' && part[0] <= '9':
+			// Step numbers and ranges
+		default:
+			num, err := resolveStepRef(commands, part)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = strconv.Itoa(num)
+		}
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// resolveStepRef returns the number of the step with the given id or number
+func resolveStepRef(commands []repo.Command, ref string) (int, error) {
+	ref = strings.TrimSpace(ref)
+	if isStepNumber(ref) {
+		num, _ := strconv.Atoi(ref)
+		if num < 1 || num > len(commands) {
+			return 0, fmt.Errorf("step %d does not exist (the set has %d steps)", num, len(commands))
+		}
+		return num, nil
+	}
+	for i, cmd := range commands {
+		if cmd.ID == ref {
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown step id '%s'", ref)
+}
+
+// resolveStepRange returns the numbers of the first and last step of a
+// --from/--to selection. Either end may be empty for the first or last step.
+func resolveStepRange(commands []repo.Command, from, to string) (int, int, error) {
+	first, last := 1, len(commands)
+	var err error
+	if from != "" {
+		if first, err = resolveStepRef(commands, from); err != nil {
+			return 0, 0, fmt.Errorf("invalid --from: %w", err)
+		}
+	}
+	if to != "" {
+		if last, err = resolveStepRef(commands, to); err != nil {
+			return 0, 0, fmt.Errorf("invalid --to: %w", err)
+		}
+	}
+	if first > last {
+		return 0, 0, fmt.Errorf("--from step %d comes after --to step %d", first, last)
+	}
+	return first, last, nil
+}
+
+// filterStepRange keeps the commands whose step numbers lie between first and
+// last, inclusive
+func filterStepRange(commands []repo.Command, indices []int, first, last int) ([]repo.Command, []int) {
+	var filtered []repo.Command
+	var filteredIndices []int
+	for i, cmd := range commands {
+		if indices[i] >= first && indices[i] <= last {
+			filtered = append(filtered, cmd)
+			filteredIndices = append(filteredIndices, indices[i])
+		}
+	}
+	return filtered, filteredIndices
+}
+
+// describeStepRange describes a --from/--to selection for previews
+func describeStepRange(from, to string) string {
+	switch {
+	case from != "" && to != "":
+		return fmt.Sprintf("from %s to %s", from, to)
+	case from != "":
+		return fmt.Sprintf("from %s to the end", from)
+	}
+	return fmt.Sprintf("up to %s", to)
+}
+
+// stepHeading returns a step's number and description followed by its id and
+// tags, as previews list steps
+func stepHeading(cmd repo.Command, num int) string {
+	var labels []string
+	if cmd.ID != "" {
+		labels = append(labels, "id: "+cmd.ID)
+	}
+	if len(cmd.Tags) > 0 {
+		labels = append(labels, "tags: "+strings.Join(cmd.Tags, ", "))
+	}
+	if len(labels) == 0 {
+		return fmt.Sprintf("%d. %s", num, cmd.Description)
+	}
+	return fmt.Sprintf("%d. %s (%s)", num, cmd.Description, strings.Join(labels, "; "))
+}
+
+// hasTag reports whether a step is tagged with the given tag
+func hasTag(cmd repo.Command, tag string) bool {
+	for _, t := range cmd.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// isStepNumber reports whether s consists of digits only
+func isStepNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// stepLabel returns the id of a step, or its number if it has none
+func stepLabel(cmd repo.Command, num int) string {
+	if cmd.ID != "" {
+		return cmd.ID
+	}
+	return strconv.Itoa(num)
+}
+
+// lineWriter passes what is written to it on line by line, so the output of
+// steps running at the same time is never split mid-line
+type lineWriter struct {
+	emit func(line []byte) // Called with each line, without its newline
+	buf  []byte
+}
+
+// newPrefixWriter returns a lineWriter that prefixes every line, so the output
+// of steps running at the same time can be told apart. Lines are written whole,
+// under a lock shared by all writers writing to the same terminal.
+func newPrefixWriter(mu *sync.Mutex, out io.Writer, prefix string) *lineWriter {
+	return &lineWriter{emit: func(line []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(line) > 0 {
+			_, _ = io.WriteString(out, prefix) // Keep blank lines blank
+		}
+		_, _ = out.Write(append(line, '\n'))
+	}}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush passes on a trailing line that did not end with a newline
+func (w *lineWriter) flush() {
+	if len(w.buf) > 0 {
+		w.emit(w.buf)
+		w.buf = nil
+	}
+}
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+func TestResolveDependencies(t *testing.T) {
+	commands := []repo.Command{
+		{ID: "install", Description: "Install"},
+		{ID: "tools", Description: "Tools"},
+		{Description: "Configure", DependsOn: []string{"install", "2"}},
+		{ID: "verify", Description: "Verify", DependsOn: []string{"3"}},
+	}
+
+	deps, err := resolveDependencies(commands)
+	if err != nil {
+		t.Fatalf("resolveDependencies failed: %v", err)
+	}
+	expected := [][]int{nil, nil, {0, 1}, {2}}
+	if !reflect.DeepEqual(deps, expected) {
+		t.Errorf("resolveDependencies = %v, expected %v", deps, expected)
+	}
+
+	invalid := []struct {
+		name     string
+		commands []repo.Command
+		errPart  string
+	}{
+		{"unknown", []repo.Command{{DependsOn: []string{"missing"}}}, "unknown step 'missing'"},
+		{"out of range", []repo.Command{{DependsOn: []string{"5"}}}, "unknown step '5'"},
+		{"self", []repo.Command{{ID: "a", DependsOn: []string{"a"}}}, "itself"},
+		{"duplicate id", []repo.Command{{ID: "a"}, {ID: "a"}}, "already used"},
+		{"invalid id", []repo.Command{{ID: "1st"}}, "invalid id"},
+		{"cycle", []repo.Command{
+			{ID: "a", DependsOn: []string{"c"}},
+			{ID: "b", DependsOn: []string{"a"}},
+			{ID: "c", DependsOn: []string{"b"}},
+		}, "dependency cycle: a -> c -> b -> a"},
+	}
+	for _, tt := range invalid {
+		_, err := resolveDependencies(tt.commands)
+		if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.errPart, err)
+		}
+	}
+}
+
+func TestDependencyOrder(t *testing.T) {
+	// Step 0 depends on step 2, the rest keep their order
+	order := dependencyOrder([][]int{{2}, nil, nil, {0}})
+	expected := []int{1, 2, 0, 3}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("dependencyOrder = %v, expected %v", order, expected)
+	}
+}
+
+func TestResolveStepIDs(t *testing.T) {
+	commands := []repo.Command{
+		{ID: "install"},
+		{Tags: []string{"config"}},
+		{ID: "verify", Tags: []string{"verify", "config"}},
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+		hasError bool
+	}{
+		{"", "", false},
+		{"1,3", "1,3", false},
+		{"1-2", "1-2", false},
+		{"install,verify", "1,3", false},
+		{"install, 2", "1, 2", false},
+		{"missing", "", true},
+		{"tag:verify", "3", false},
+		{"tag:config,install", "2,3,1", false},
+		{"tag:missing", "", true},
+		{"2-", "2-3", false},
+		{"-2", "1-2", false},
+	}
+
+	for _, tt := range tests {
+		result, err := resolveStepIDs(commands, tt.input)
+		if tt.hasError {
+			if err == nil {
+				t.Errorf("resolveStepIDs(%q) expected error, got nil", tt.input)
+			}
+			continue
+		}
+		if err != nil || result != tt.expected {
+			t.Errorf("resolveStepIDs(%q) = %q, %v, expected %q", tt.input, result, err, tt.expected)
+		}
+	}
+}
+
+func TestResolveStepRange(t *testing.T) {
+	commands := []repo.Command{{ID: "install"}, {}, {ID: "verify"}, {}}
+
+	tests := []struct {
+		from, to    string
+		first, last int
+		hasError    bool
+	}{
+		{"", "", 1, 4, false},
+		{"2", "", 2, 4, false},
+		{"", "verify", 1, 3, false},
+		{"install", "3", 1, 3, false},
+		{"verify", "install", 0, 0, true},
+		{"5", "", 0, 0, true},
+		{"", "missing", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		first, last, err := resolveStepRange(commands, tt.from, tt.to)
+		if tt.hasError {
+			if err == nil {
+				t.Errorf("resolveStepRange(%q, %q) expected error, got nil", tt.from, tt.to)
+			}
+			continue
+		}
+		if err != nil || first != tt.first || last != tt.last {
+			t.Errorf("resolveStepRange(%q, %q) = %d, %d, %v, expected %d, %d", tt.from, tt.to, first, last, err, tt.first, tt.last)
+		}
+	}
+
+	// The range applies to the step numbers left after --skip/--only
+	filtered, indices := filterStepRange(commands[1:], []int{2, 3, 4}, 1, 3)
+	if len(filtered) != 2 || indices[0] != 2 || indices[1] != 3 {
+		t.Errorf("Unexpected steps in range: %v", indices)
+	}
+}
+
+func TestStepHeading(t *testing.T) {
+	tests := []struct {
+		cmd      repo.Command
+		expected string
+	}{
+		{repo.Command{Description: "Install Go"}, "1. Install Go"},
+		{repo.Command{ID: "install", Description: "Install Go"}, "1. Install Go (id: install)"},
+		{repo.Command{ID: "verify", Tags: []string{"verify", "slow"}, Description: "Verify"}, "1. Verify (id: verify; tags: verify, slow)"},
+	}
+
+	for _, tt := range tests {
+		if result := stepHeading(tt.cmd, 1); result != tt.expected {
+			t.Errorf("stepHeading(%+v) = %q, expected %q", tt.cmd, result, tt.expected)
+		}
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := newPrefixWriter(&sync.Mutex{}, &out, "[go] ")
+
+	_, _ = w.Write([]byte("first line\nsecond "))
+	_, _ = w.Write([]byte("line\n\npartial"))
+	w.flush()
+
+	expected := "[go] first line\n[go] second line\n\n[go] partial\n"
+	if out.String() != expected {
+		t.Errorf("prefixWriter output = %q, expected %q", out.String(), expected)
+	}
+}
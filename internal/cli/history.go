@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var historyLimitFlag int
+
+var historyCmd = &cobra.Command{
+	Use:   "history [command-set-name]",
+	Short: "List past runs",
+	Long: `List past runs of all command sets, or of one command set, most recent first.
+
+Every run is recorded under ~/.shelldock/history with its version,
+platform, arguments (secrets redacted) and the outcome of each step.
+
+Examples:
+  shelldock history                                  # List recent runs
+  shelldock history docker                           # List runs of a command set
+  shelldock history show docker-20240101-120000      # Show a run in detail`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		set := ""
+		if len(args) > 0 {
+			set = args[0]
+		}
+
+		records, err := listRunRecords(set)
+		handleError(err)
+
+		if len(records) == 0 {
+			if set != "" {
+				fmt.Printf("No runs of '%s' in history.\n", set)
+			} else {
+				fmt.Println("No runs in history.")
+			}
+			return
+		}
+
+		if historyLimitFlag > 0 && len(records) > historyLimitFlag {
+			records = records[:historyLimitFlag]
+		}
+
+		fmt.Println("Run history:")
+		fmt.Println()
+		for _, r := range records {
+			icon := "✅"
+			if r.Status != stepSuccess {
+				icon = "❌"
+			}
+			fmt.Printf("  %s %s (%s@%s, %s, %s, %s)\n", icon, r.ID, r.Set, r.Version, r.Status, r.StartedAt.Format("2006-01-02 15:04:05"), r.duration())
+		}
+		fmt.Println()
+		fmt.Println("💡 Show details with: shelldock history show <run-id>")
+	},
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show [run-id]",
+	Short: "Show the details of a past run",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r, err := loadRunRecord(args[0])
+		handleError(err)
+
+		fmt.Printf("\n🧾 Run: %s\n", r.ID)
+		fmt.Printf("📦 Command Set: %s\n", r.Set)
+		fmt.Printf("🔢 Version: %s\n", r.Version)
+		fmt.Printf("🖥️  Platform: %s\n", r.Platform)
+		fmt.Printf("👤 User: %s@%s\n", r.User, r.Host)
+		fmt.Printf("🕒 Started: %s (took %s)\n", r.StartedAt.Format("2006-01-02 15:04:05"), r.duration())
+		fmt.Printf("📊 Status: %s\n", r.Status)
+		if r.Resumed > 0 {
+			fmt.Printf("🔁 Resumed: %d time(s)\n", r.Resumed)
+		}
+		if r.SkipSteps != "" {
+			fmt.Printf("⏭️  Skipping steps: %s\n", r.SkipSteps)
+		} else if r.OnlySteps != "" {
+			fmt.Printf("🎯 Running only steps: %s\n", r.OnlySteps)
+		}
+		if r.FromStep != "" || r.ToStep != "" {
+			fmt.Printf("📍 Running steps %s\n", describeStepRange(r.FromStep, r.ToStep))
+		}
+
+		if len(r.Args) > 0 {
+			names := make([]string, 0, len(r.Args))
+			for name := range r.Args {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			fmt.Printf("\n📝 Arguments:\n")
+			for _, name := range names {
+				fmt.Printf("  %s=%s\n", name, r.Args[name])
+			}
+		}
+
+		fmt.Printf("\n📋 Steps:\n\n")
+		for _, step := range r.Steps {
+			label := step.Description
+			if step.ID != "" {
+				label = fmt.Sprintf("%s (id: %s)", label, step.ID)
+			}
+			fmt.Printf("  %d. %s\n", step.Step, label)
+			detail := step.Status
+			if step.Status == stepFailed {
+				detail = fmt.Sprintf("%s, exit code %d", detail, step.ExitCode)
+			}
+			if step.DurationMS > 0 {
+				detail = fmt.Sprintf("%s, %s", detail, time.Duration(step.DurationMS)*time.Millisecond)
+			}
+			fmt.Printf("     %s\n", detail)
+		}
+
+		if len(r.RolledBack) > 0 {
+			steps := make([]string, len(r.RolledBack))
+			for i, step := range r.RolledBack {
+				steps[i] = fmt.Sprintf("%d", step)
+			}
+			fmt.Printf("\n↩️  Rolled back steps: %s\n", strings.Join(steps, ", "))
+		}
+		fmt.Println()
+		fmt.Printf("💡 Run again with: shelldock rerun %s\n", r.ID)
+	},
+}
+
+func init() {
+	historyCmd.Flags().IntVarP(&historyLimitFlag, "limit", "n", 20, "Maximum number of runs to list (0 for all)")
+	historyCmd.AddCommand(historyShowCmd)
+}
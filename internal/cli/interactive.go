@@ -0,0 +1,252 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Choices offered for a step in --step mode
+const (
+	stepRun   = "run"
+	stepSkip  = "skip"
+	stepRetry = "retry"
+	stepAbort = "abort"
+)
+
+// errAborted is the error of a step at which the run was aborted in --step mode
+var errAborted = errors.New("aborted by user")
+
+// stepper walks through a run step by step, asking before each step and after
+// each failure what to do. Steps run one at a time, so it is not locked.
+type stepper struct {
+	platform string
+	in       *bufio.Reader // Answers to the questions, usually the terminal
+}
+
+// newStepper returns a stepper reading answers from in
+func newStepper(platform string, in io.Reader) *stepper {
+	return &stepper{platform: platform, in: bufio.NewReader(in)}
+}
+
+// beforeStep asks whether to run, skip, edit or inspect a step, or to abort the
+// run. It returns the choice and the command to run, which may have been edited.
+func (p *stepper) beforeStep(step *plannedStep, command string, shown renderedStep, report stepReporter) (string, string) {
+	for {
+		switch p.ask("Run this step? [r]un, [s]kip, [e]dit, [d]etails, [a]bort", "r") {
+		case "r", "run":
+			return stepRun, command
+		case "s", "skip":
+			return stepSkip, command
+		case "e", "edit":
+			edited, err := editCommand(command)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Failed to edit the command: %v\n", err)
+				continue
+			}
+			if edited == "" {
+				fmt.Println("⚠️  The edited command is empty, keeping the step unchanged")
+				continue
+			}
+			command = edited
+			report.command(command)
+		case "d", "details":
+			p.printDetails(step, shown)
+		case "a", "abort":
+			return stepAbort, command
+		default:
+			fmt.Println("Please answer r, s, e, d or a.")
+		}
+	}
+}
+
+// afterFailure asks whether to retry or skip a failed step, open a shell to
+// look into the failure, or abort the run
+func (p *stepper) afterFailure(err error) string {
+	fmt.Fprintf(os.Stderr, "\n⚠️  Command failed: %v\n", err)
+	for {
+		switch p.ask("What now? [r]etry, [s]kip, [d]ebug shell, [a]bort", "a") {
+		case "r", "retry":
+			return stepRetry
+		case "s", "skip":
+			return stepSkip
+		case "d", "debug":
+			if err := openDebugShell(); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Debug shell failed: %v\n", err)
+			}
+		case "a", "abort":
+			return stepAbort
+		default:
+			fmt.Println("Please answer r, s, d or a.")
+		}
+	}
+}
+
+// ask prints a question and returns the lower-cased answer, or the default for
+// an empty answer. Unreadable input aborts the run.
+func (p *stepper) ask(question, defaultAnswer string) string {
+	fmt.Printf("%s (%s): ", question, defaultAnswer)
+	_ = os.Stdout.Sync()
+
+	response, err := p.in.ReadString('\n')
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nError reading input: %v\n", err)
+		return "a"
+	}
+	response = strings.TrimSpace(strings.ToLower(response))
+	if response == "" {
+		return defaultAnswer
+	}
+	return response
+}
+
+// printDetails shows everything about a step that affects how it runs.
+// Secret argument values are masked.
+func (p *stepper) printDetails(step *plannedStep, shown renderedStep) {
+	cmd := step.cmd
+	fmt.Printf("\n  Step:        %d\n", step.num)
+	if cmd.ID != "" {
+		fmt.Printf("  ID:          %s\n", cmd.ID)
+	}
+	fmt.Printf("  Description: %s\n", cmd.Description)
+	fmt.Printf("  Template:    %s\n", indentCommand(getCommandForPlatform(cmd, p.platform), "               "))
+	fmt.Printf("  Command:     %s\n", indentCommand(shown.command, "               "))
+	if shown.script != "" {
+		fmt.Printf("  Interpreter: %s\n", shellWords(shown.interp))
+	}
+	if shown.check != "" {
+		fmt.Printf("  Check:       %s\n", shown.check)
+	}
+	if cmd.When != "" {
+		fmt.Printf("  When:        %s\n", cmd.When)
+	}
+	if len(cmd.DependsOn) > 0 {
+		fmt.Printf("  Depends on:  %s\n", strings.Join(cmd.DependsOn, ", "))
+	}
+	if desc := step.policy.String(); desc != "" {
+		fmt.Printf("  Policy:      %s\n", desc)
+	}
+	if cmd.SkipOnError {
+		fmt.Printf("  On error:    continue (skip_on_error)\n")
+	}
+	if cmd.Register != "" {
+		fmt.Printf("  Register:    {{%s}}\n", cmd.Register)
+	}
+	if shown.undo != "" {
+		fmt.Printf("  Undo:        %s\n", shown.undo)
+	}
+
+	if len(step.args) > 0 {
+		secrets := make(map[string]bool)
+		for _, argDef := range cmd.Args {
+			if argDef.IsSecret() {
+				secrets[argDef.Name] = true
+			}
+		}
+		names := make([]string, 0, len(step.args))
+		for name := range step.args {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Printf("  Arguments:\n")
+		for _, name := range names {
+			value := step.args[name]
+			if secrets[name] && value != "" {
+				value = "****"
+			}
+			fmt.Printf("    %s = %s\n", name, value)
+		}
+	}
+	fmt.Println()
+}
+
+// editCommand opens a command in the user's editor and returns the edited
+// command without trailing whitespace
+func editCommand(command string) (string, error) {
+	file, err := os.CreateTemp("", "shelldock-step-*.sh")
+	if err != nil {
+		return "", err
+	}
+	path := file.Name()
+	defer os.Remove(path)
+
+	_, err = file.WriteString(command + "\n")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := openEditor(path); err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), " \t\r\n"), nil
+}
+
+// openEditor opens a file in the user's editor ($VISUAL or $EDITOR, vi by
+// default) and waits for it to be closed
+func openEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	// The editor may be given with arguments, e.g. "code --wait"
+	fields := strings.Fields(editor)
+	editCmd := exec.Command(fields[0], append(fields[1:], path)...)
+	editCmd.Stdin = os.Stdin
+	editCmd.Stdout = os.Stdout
+	editCmd.Stderr = os.Stderr
+	if err := editCmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", editor, err)
+	}
+	return nil
+}
+
+// openDebugShell starts an interactive shell ($SHELL, or sh) and waits for the
+// user to leave it. Secret arguments are available in it as they are to steps.
+func openDebugShell() error {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "sh"
+		if runtime.GOOS == "windows" {
+			if shell = os.Getenv("COMSPEC"); shell == "" {
+				shell = "cmd.exe"
+			}
+		}
+	}
+
+	fmt.Printf("🐚 Opening %s to debug the step, exit it to return to the run\n", shell)
+	shellCmd := exec.Command(shell)
+	shellCmd.Stdin = os.Stdin
+	shellCmd.Stdout = os.Stdout
+	shellCmd.Stderr = os.Stderr
+	err := shellCmd.Run()
+	fmt.Println()
+
+	// Leaving the shell with the exit status of the last command is not a failure
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil
+	}
+	return err
+}
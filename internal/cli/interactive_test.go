@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+func TestEditCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the editor")
+	}
+
+	// The editor replaces one word of the file with another
+	editor := filepath.Join(t.TempDir(), "editor")
+	script := "#!/bin/sh\nsed \"s/$1/$2/\" \"$3\" > \"$3.new\" && mv \"$3.new\" \"$3\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write editor: %v", err)
+	}
+
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", editor+" 2G 4G")
+	edited, err := editCommand("sudo fallocate -l 2G /swapfile")
+	if err != nil || edited != "sudo fallocate -l 4G /swapfile" {
+		t.Errorf("Expected the edited command, got %q, %v", edited, err)
+	}
+
+	// VISUAL takes precedence over EDITOR
+	t.Setenv("VISUAL", editor+" swapfile swap.img")
+	edited, err = editCommand("sudo fallocate -l 2G /swapfile")
+	if err != nil || edited != "sudo fallocate -l 2G /swap.img" {
+		t.Errorf("Expected the command edited with VISUAL, got %q, %v", edited, err)
+	}
+
+	t.Setenv("VISUAL", "false")
+	if _, err := editCommand("echo hi"); err == nil {
+		t.Error("Expected an error when the editor fails")
+	}
+}
+
+func TestStepperBeforeStep(t *testing.T) {
+	step := &plannedStep{cmd: repo.Command{Description: "Create swap file", Command: "fallocate -l {{size}} /swapfile"}, num: 1}
+	command := "fallocate -l 2G /swapfile"
+	tests := []struct {
+		input   string
+		choice  string
+		command string
+	}{
+		{"\n", stepRun, command},
+		{"run\n", stepRun, command},
+		{"s\n", stepSkip, command},
+		{"A\n", stepAbort, command},
+		{"x\nr\n", stepRun, command},
+		{"d\ns\n", stepSkip, command},
+		{"", stepAbort, command},
+	}
+
+	for _, tt := range tests {
+		report := (&textReporter{}).step(step, 1)
+		choice, got := newStepper("ubuntu", strings.NewReader(tt.input)).beforeStep(step, command, renderedStep{command: command}, report)
+		if choice != tt.choice || got != tt.command {
+			t.Errorf("beforeStep() with input %q = %q, %q, want %q, %q", tt.input, choice, got, tt.choice, tt.command)
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	// Editing the command runs the edited one
+	editor := filepath.Join(t.TempDir(), "editor")
+	script := "#!/bin/sh\nsed \"s/2G/4G/\" \"$1\" > \"$1.new\" && mv \"$1.new\" \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write editor: %v", err)
+	}
+	t.Setenv("VISUAL", editor)
+	report := (&textReporter{}).step(step, 1)
+	choice, got := newStepper("ubuntu", strings.NewReader("e\nr\n")).beforeStep(step, command, renderedStep{command: command}, report)
+	if choice != stepRun || got != "fallocate -l 4G /swapfile" {
+		t.Errorf("Expected the edited command to run, got %q, %q", choice, got)
+	}
+}
+
+func TestStepperAfterFailure(t *testing.T) {
+	tests := []struct {
+		input  string
+		choice string
+	}{
+		{"\n", stepAbort},
+		{"r\n", stepRetry},
+		{"retry\n", stepRetry},
+		{"s\n", stepSkip},
+		{"a\n", stepAbort},
+		{"x\ns\n", stepSkip},
+		{"", stepAbort},
+	}
+
+	for _, tt := range tests {
+		if choice := newStepper("ubuntu", strings.NewReader(tt.input)).afterFailure(errors.New("exit status 1")); choice != tt.choice {
+			t.Errorf("afterFailure() with input %q = %q, want %q", tt.input, choice, tt.choice)
+		}
+	}
+}
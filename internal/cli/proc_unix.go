@@ -0,0 +1,74 @@
+//go:build !windows
+
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// setProcessGroup starts the command in a new process group. When the command
+// reads from the terminal, its group is made the terminal's foreground group,
+// so it can still prompt (e.g., sudo asking for a password) and gets Ctrl-C.
+// It returns the terminal to hand back with restoreTerminal, or nil.
+func setProcessGroup(cmd *exec.Cmd) *os.File {
+	attr := &syscall.SysProcAttr{Setpgid: true}
+	tty, ok := cmd.Stdin.(*os.File)
+	if ok && term.IsTerminal(int(tty.Fd())) {
+		attr.Foreground = true
+		attr.Ctty = int(tty.Fd())
+	} else {
+		tty = nil
+	}
+	cmd.SysProcAttr = attr
+	return tty
+}
+
+// restoreTerminal makes shelldock's process group the terminal's foreground
+// group again after a command that was given the terminal
+func restoreTerminal(tty *os.File) {
+	if tty == nil {
+		return
+	}
+	// A background group changing the foreground group is stopped by SIGTTOU
+	// unless it ignores it
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	unix.IoctlSetPointerInt(int(tty.Fd()), unix.TIOCSPGRP, unix.Getpgrp())
+}
+
+// killProcessGroup kills the command and every process it spawned
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// forwardSignals passes SIGINT and SIGTERM sent to shelldock on to the
+// command's process group until stop is called, so interrupting a run does not
+// leave the command running
+func forwardSignals(cmd *exec.Cmd) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				syscall.Kill(-cmd.Process.Pid, sig.(syscall.Signal))
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package cli
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on Windows
+func setProcessGroup(cmd *exec.Cmd) *os.File {
+	return nil
+}
+
+// restoreTerminal is a no-op on Windows
+func restoreTerminal(tty *os.File) {}
+
+// killProcessGroup kills the command process
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+// forwardSignals is a no-op on Windows, where Ctrl-C reaches every process
+// attached to the console
+func forwardSignals(cmd *exec.Cmd) (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shelldock/shelldock/internal/config"
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+const historyDirName = "history"
+
+// redactedValue replaces the values of secret arguments in the history
+const redactedValue = "****"
+
+// secretArgPattern matches argument names whose values are not written to the
+// history even if they are not declared secret, e.g. db_password or API_KEY
+var secretArgPattern = regexp.MustCompile(`(?i)(^|_)(pass|passwd|password|passphrase|secret|token|api_?key|private_?key|credentials?)(_|$)`)
+
+// runRecord is the history entry of a run, written when the run finishes
+type runRecord struct {
+	ID         string            `json:"id"`
+	Set        string            `json:"set"`
+	Version    string            `json:"version"`
+	Platform   string            `json:"platform"`
+	Local      bool              `json:"local,omitempty"`
+	SkipSteps  string            `json:"skip_steps,omitempty"`
+	OnlySteps  string            `json:"only_steps,omitempty"`
+	FromStep   string            `json:"from_step,omitempty"`
+	ToStep     string            `json:"to_step,omitempty"`
+	User       string            `json:"user"`
+	Host       string            `json:"host"`
+	Args       map[string]string `json:"args,omitempty"`     // Secret values are redacted
+	Redacted   []string          `json:"redacted,omitempty"` // Arguments whose values were not recorded
+	Status     string            `json:"status"`
+	Steps      []stepRecord      `json:"steps"`
+	RolledBack []int             `json:"rolled_back,omitempty"` // Steps whose undo commands ran
+	Resumed    int               `json:"resumed,omitempty"`     // Number of times the run was resumed
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+}
+
+// stepRecord is the outcome of a step in the history
+type stepRecord struct {
+	Step        int    `json:"step"`
+	ID          string `json:"id,omitempty"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+	ExitCode    int    `json:"exit_code"`
+	DurationMS  int64  `json:"duration_ms"`
+}
+
+// getHistoryDir returns the directory holding run records (~/.shelldock/history)
+func getHistoryDir() (string, error) {
+	dir, err := config.GetShellDockDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, historyDirName), nil
+}
+
+// newRunRecord builds the history entry of a finished run from its checkpoint.
+// Results are taken before a rollback, so rolledBack lists the undone steps.
+func newRunRecord(checkpoint *runCheckpoint, commands []repo.Command, results map[int]stepResult, status string, rolledBack []int) *runRecord {
+	record := &runRecord{
+		ID:         checkpoint.ID,
+		Set:        checkpoint.Set,
+		Version:    checkpoint.Version,
+		Platform:   checkpoint.Platform,
+		Local:      checkpoint.Local,
+		SkipSteps:  checkpoint.SkipSteps,
+		OnlySteps:  checkpoint.OnlySteps,
+		FromStep:   checkpoint.FromStep,
+		ToStep:     checkpoint.ToStep,
+		User:       config.DetectUser(),
+		Status:     status,
+		Steps:      []stepRecord{},
+		RolledBack: rolledBack,
+		StartedAt:  checkpoint.StartedAt,
+		FinishedAt: time.Now(),
+	}
+	record.Host, _ = os.Hostname()
+	record.Args, record.Redacted = redactArgs(checkpoint.Args, secretArgNames(commands))
+
+	nums := make([]int, 0, len(results))
+	for num := range results {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		step := stepRecord{
+			Step:       num,
+			Status:     results[num].Status,
+			ExitCode:   results[num].ExitCode,
+			DurationMS: results[num].DurationMS,
+		}
+		if num >= 1 && num <= len(commands) {
+			step.ID = commands[num-1].ID
+			step.Description = commands[num-1].Description
+		}
+		record.Steps = append(record.Steps, step)
+	}
+	return record
+}
+
+// redactArgs returns a copy of the arguments with the values of secrets
+// replaced, those declared secret and those whose names look like secrets, and
+// the sorted names of the replaced ones
+func redactArgs(args map[string]string, secrets map[string]bool) (map[string]string, []string) {
+	redacted := make(map[string]string, len(args))
+	var names []string
+	for name, value := range args {
+		if secrets[name] || secretArgPattern.MatchString(name) {
+			value = redactedValue
+			names = append(names, name)
+		}
+		redacted[name] = value
+	}
+	sort.Strings(names)
+	return redacted, names
+}
+
+// duration returns how long the run took
+func (r *runRecord) duration() time.Duration {
+	d := r.FinishedAt.Sub(r.StartedAt)
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(time.Second)
+}
+
+// save writes the record to ~/.shelldock/history/<run-id>.json. A resumed run
+// updates the record of the original run.
+func (r *runRecord) save() error {
+	dir, err := getHistoryDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	if previous, err := loadRunRecord(r.ID); err == nil {
+		r.Resumed = previous.Resumed + 1
+		r.RolledBack = append(previous.RolledBack, r.RolledBack...)
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run record: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, r.ID+".json"), data, 0600); err != nil {
+		return fmt.Errorf("failed to write run record: %w", err)
+	}
+	return nil
+}
+
+// loadRunRecord loads the history entry of a run by its ID
+func loadRunRecord(id string) (*runRecord, error) {
+	dir, err := getHistoryDir()
+	if err != nil {
+		return nil, err
+	}
+	if id == "" || strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("run '%s' not found in history", id)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("run '%s' not found in history", id)
+		}
+		return nil, fmt.Errorf("failed to read run record: %w", err)
+	}
+
+	var r runRecord
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse run record: %w", err)
+	}
+	return &r, nil
+}
+
+// listRunRecords returns the history of a command set, or of all runs if set
+// is empty, most recent first
+func listRunRecords(set string) ([]*runRecord, error) {
+	dir, err := getHistoryDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*runRecord{}, nil
+		}
+		return nil, fmt.Errorf("failed to read history directory: %w", err)
+	}
+
+	var records []*runRecord
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		r, err := loadRunRecord(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			continue // Skip unreadable records
+		}
+		if set == "" || r.Set == set {
+			records = append(records, r)
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].StartedAt.After(records[j].StartedAt)
+	})
+	return records, nil
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+func TestRunRecord(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Temporarily override HOME
+	originalHome := os.Getenv("HOME")
+	defer func() {
+		if originalHome != "" {
+			_ = os.Setenv("HOME", originalHome)
+		}
+	}()
+
+	_ = os.Setenv("HOME", tmpDir)
+
+	commands := []repo.Command{
+		{ID: "install", Description: "Install"},
+		{Description: "Configure", Args: []repo.ArgumentDef{{Name: "pin", Secret: true}}},
+	}
+	checkpoint, err := newRunCheckpoint("app", "v1", "ubuntu")
+	if err != nil {
+		t.Fatalf("newRunCheckpoint failed: %v", err)
+	}
+	checkpoint.Args["name"] = "John"
+	checkpoint.Args["DB_PASSWORD"] = "hunter2"
+	checkpoint.Args["api_key"] = "abc"
+	checkpoint.Args["pin"] = "1234"
+	checkpoint.Args["bypass_cache"] = "true"
+	results := map[int]stepResult{
+		2: {Status: stepFailed, ExitCode: 3, DurationMS: 120},
+		1: {Status: stepSuccess, DurationMS: 40},
+	}
+
+	if err := newRunRecord(checkpoint, commands, results, stepFailed, []int{1}).save(); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	loaded, err := loadRunRecord(checkpoint.ID)
+	if err != nil {
+		t.Fatalf("loadRunRecord failed: %v", err)
+	}
+	if loaded.Set != "app" || loaded.Version != "v1" || loaded.Status != stepFailed {
+		t.Errorf("Unexpected record: %+v", loaded)
+	}
+	if loaded.Args["name"] != "John" || loaded.Args["DB_PASSWORD"] != redactedValue || loaded.Args["api_key"] != redactedValue || loaded.Args["pin"] != redactedValue {
+		t.Errorf("Expected secrets to be redacted, got %v", loaded.Args)
+	}
+	if loaded.Args["bypass_cache"] != "true" || strings.Join(loaded.Redacted, ",") != "DB_PASSWORD,api_key,pin" {
+		t.Errorf("Expected only secrets listed as redacted, got %v", loaded.Redacted)
+	}
+	if len(loaded.Steps) != 2 || loaded.Steps[0].ID != "install" || loaded.Steps[1].ExitCode != 3 || loaded.Steps[1].DurationMS != 120 {
+		t.Errorf("Unexpected steps: %+v", loaded.Steps)
+	}
+
+	// Resuming the run updates its record
+	if err := newRunRecord(checkpoint, commands, map[int]stepResult{1: {Status: stepSuccess}, 2: {Status: stepSuccess}}, stepSuccess, nil).save(); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	loaded, err = loadRunRecord(checkpoint.ID)
+	if err != nil {
+		t.Fatalf("loadRunRecord failed: %v", err)
+	}
+	if loaded.Status != stepSuccess || loaded.Resumed != 1 || len(loaded.RolledBack) != 1 {
+		t.Errorf("Unexpected resumed record: %+v", loaded)
+	}
+
+	// A new run does not reuse the ID of a recorded run
+	next, err := newRunCheckpoint("app", "v1", "ubuntu")
+	if err != nil {
+		t.Fatalf("newRunCheckpoint failed: %v", err)
+	}
+	if next.ID == checkpoint.ID {
+		t.Errorf("Expected a new run ID, got %q again", next.ID)
+	}
+
+	records, err := listRunRecords("app")
+	if err != nil || len(records) != 1 {
+		t.Errorf("Expected 1 record for 'app', got %d (%v)", len(records), err)
+	}
+	if records, _ := listRunRecords("nginx"); len(records) != 0 {
+		t.Errorf("Expected no records for 'nginx', got %d", len(records))
+	}
+	if _, err := loadRunRecord("missing"); err == nil {
+		t.Error("Expected error loading missing record")
+	}
+}
+
+func TestWithPrompts(t *testing.T) {
+	commands := []repo.Command{
+		{Args: []repo.ArgumentDef{{Name: "token", Default: "none"}, {Name: "name"}}},
+		{Args: []repo.ArgumentDef{{Name: "token", Prompt: "API token"}}},
+	}
+
+	// Redacted arguments are asked for again instead of taking their default
+	result := withPrompts(commands, []string{"token"})
+	if result[0].Args[0].Prompt != "Enter token" || result[0].Args[1].Prompt != "" || result[1].Args[0].Prompt != "API token" {
+		t.Errorf("Unexpected prompts: %+v", result)
+	}
+	if commands[0].Args[0].Prompt != "" {
+		t.Error("Expected the commands to be left unchanged")
+	}
+}
@@ -0,0 +1,416 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Output formats of a run
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// Reasons for skipping a step
+const (
+	skipPlatform = "platform" // No command for the platform
+	skipWhen     = "when"     // Condition not met
+	skipCheck    = "check"    // Already satisfied
+	skipUser     = "user"     // Skipped in --step mode
+	skipEdited   = "edit"     // Removed from the plan with --edit
+)
+
+// runReporter presents the progress of a run, as text for people or as
+// JSON events for tools. Steps may report concurrently.
+type runReporter interface {
+	runStarted(checkpoint *runCheckpoint, steps []*plannedStep)
+	step(step *plannedStep, total int) stepReporter
+	runFinished(checkpoint *runCheckpoint, failures []stepFailure, resumeFrom int)
+
+	rollbackStarted(plan []rollbackStep)
+	// undoStarted returns the streams the undo command runs with
+	undoStarted(undo rollbackStep, position, total int) stepStreams
+	undoFinished(undo rollbackStep, err error)
+	rollbackFinished(err error)
+}
+
+// stepReporter presents the progress of one step
+type stepReporter interface {
+	// streams returns the streams the step's commands run with
+	streams() stepStreams
+	started()
+	command(command string)
+	skipped(reason, detail string)
+	retrying(attempt, attempts int, err error, delay time.Duration)
+	// finished reports a step that completed, including failures with skip_on_error
+	finished(result stepResult, duration time.Duration, err error)
+	// failed reports a step whose failure stops the run
+	failed(result stepResult, duration time.Duration, err error)
+	close()
+}
+
+// newRunReporter returns the reporter for an output format
+func newRunReporter(output string, parallel bool) (runReporter, error) {
+	switch output {
+	case "", outputText:
+		return &textReporter{parallel: parallel}, nil
+	case outputJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false) // Keep commands readable
+		return &jsonReporter{parallel: parallel, encoder: encoder, started: time.Now()}, nil
+	}
+	return nil, fmt.Errorf("invalid output format '%s' (use text or json)", output)
+}
+
+// textReporter prints the progress of a run to the terminal
+type textReporter struct {
+	parallel bool
+	outputMu sync.Mutex
+}
+
+func (r *textReporter) runStarted(checkpoint *runCheckpoint, steps []*plannedStep) {
+	fmt.Println("\n🚀 Executing commands...")
+	fmt.Println()
+}
+
+func (r *textReporter) step(step *plannedStep, total int) stepReporter {
+	streams := terminalStreams
+	if r.parallel {
+		// Prefix the output of steps running at the same time with their id
+		prefix := fmt.Sprintf("[%s] ", step.label)
+		streams = stepStreams{
+			stdout: newPrefixWriter(&r.outputMu, os.Stdout, prefix),
+			stderr: newPrefixWriter(&r.outputMu, os.Stderr, prefix),
+		}
+	}
+	return &textStepReporter{step: step, total: total, out: streams}
+}
+
+func (r *textReporter) runFinished(checkpoint *runCheckpoint, failures []stepFailure, resumeFrom int) {
+	if len(failures) == 0 {
+		fmt.Println("🎉 All commands executed successfully!")
+	}
+}
+
+func (r *textReporter) rollbackStarted(plan []rollbackStep) {
+	fmt.Println("\n↩️  Rolling back completed steps...")
+	fmt.Println()
+}
+
+func (r *textReporter) undoStarted(undo rollbackStep, position, total int) stepStreams {
+	fmt.Printf("[%d/%d] Undo: %s (step %d)\n", position, total, undo.Description, undo.Step)
+	fmt.Printf("$ %s\n", undo.Shown)
+	return terminalStreams
+}
+
+func (r *textReporter) undoFinished(undo rollbackStep, err error) {
+	if err == nil {
+		fmt.Println("✅ Undone")
+		fmt.Println()
+	}
+}
+
+func (r *textReporter) rollbackFinished(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Rollback stopped: %v\n", err)
+		return
+	}
+	fmt.Println("✅ Rollback complete")
+}
+
+// textStepReporter prints the progress of one step
+type textStepReporter struct {
+	step  *plannedStep
+	total int
+	out   stepStreams
+}
+
+func (r *textStepReporter) streams() stepStreams {
+	return r.out
+}
+
+func (r *textStepReporter) started() {
+	fmt.Fprintf(r.out.stdout, "[%d/%d] %s (step %d)\n", r.step.order, r.total, r.step.cmd.Description, r.step.num)
+}
+
+func (r *textStepReporter) command(command string) {
+	fmt.Fprintf(r.out.stdout, "$ %s\n", command)
+}
+
+func (r *textStepReporter) skipped(reason, detail string) {
+	switch reason {
+	case skipPlatform:
+		fmt.Fprintf(r.out.stdout, "⚠️  Skipping: No command available for platform '%s'\n\n", detail)
+	case skipWhen:
+		fmt.Fprintf(r.out.stdout, "⏭️  Condition not met, skipping (when: %s)\n\n", detail)
+	case skipCheck:
+		fmt.Fprintf(r.out.stdout, "✔️  Already satisfied, skipping (check: %s)\n\n", detail)
+	case skipUser:
+		fmt.Fprintf(r.out.stdout, "⏭️  Skipped\n\n")
+	case skipEdited:
+		fmt.Fprintf(r.out.stdout, "⏭️  Removed from the plan, skipping\n\n")
+	}
+}
+
+func (r *textStepReporter) retrying(attempt, attempts int, err error, delay time.Duration) {
+	fmt.Fprintf(r.out.stdout, "🔁 Attempt %d/%d failed (%v), retrying in %s...\n", attempt, attempts, err, delay)
+}
+
+func (r *textStepReporter) finished(result stepResult, duration time.Duration, err error) {
+	if err != nil && !r.step.cmd.SkipOnError {
+		fmt.Fprintf(r.out.stdout, "⏭️  Command failed, skipped\n\n")
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(r.out.stdout, "⚠️  Command failed but continuing (skip_on_error=true)\n\n")
+		return
+	}
+	fmt.Fprintln(r.out.stdout, "✅ Success")
+	fmt.Fprintln(r.out.stdout)
+}
+
+func (r *textStepReporter) failed(result stepResult, duration time.Duration, err error) {
+	fmt.Fprintf(r.out.stderr, "\n❌ Command failed: %v\n", err)
+}
+
+func (r *textStepReporter) close() {
+	for _, w := range []interface{}{r.out.stdout, r.out.stderr} {
+		if lw, ok := w.(*lineWriter); ok {
+			lw.flush()
+		}
+	}
+}
+
+// runEvent is one line of the JSON event stream
+type runEvent struct {
+	Event       string      `json:"event"`
+	Time        time.Time   `json:"time"`
+	RunID       string      `json:"run_id,omitempty"`
+	Set         string      `json:"set,omitempty"`
+	Version     string      `json:"version,omitempty"`
+	Platform    string      `json:"platform,omitempty"`
+	Steps       []eventStep `json:"steps,omitempty"`
+	Step        int         `json:"step,omitempty"`
+	ID          string      `json:"id,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Order       int         `json:"order,omitempty"`
+	Total       int         `json:"total,omitempty"`
+	Command     string      `json:"command,omitempty"`
+	Stream      string      `json:"stream,omitempty"`
+	Line        *string     `json:"line,omitempty"`
+	Status      string      `json:"status,omitempty"`
+	Reason      string      `json:"reason,omitempty"`
+	Detail      string      `json:"detail,omitempty"`
+	ExitCode    *int        `json:"exit_code,omitempty"`
+	DurationMS  *int64      `json:"duration_ms,omitempty"`
+	Attempt     int         `json:"attempt,omitempty"`
+	Attempts    int         `json:"attempts,omitempty"`
+	DelayMS     *int64      `json:"delay_ms,omitempty"`
+	Error       string      `json:"error,omitempty"`
+	Fatal       bool        `json:"fatal,omitempty"`
+	FailedSteps []int       `json:"failed_steps,omitempty"`
+	ResumeFrom  int         `json:"resume_from,omitempty"`
+}
+
+// eventStep describes a step in the run_start event
+type eventStep struct {
+	Step        int      `json:"step"`
+	ID          string   `json:"id,omitempty"`
+	Description string   `json:"description"`
+	DependsOn   []string `json:"depends_on,omitempty"`
+}
+
+// jsonReporter writes the progress of a run to stdout as newline-delimited JSON events.
+// Output of the steps' commands is included as output events, one per line.
+type jsonReporter struct {
+	parallel bool
+	mu       sync.Mutex
+	encoder  *json.Encoder
+	started  time.Time
+	undoOut  stepStreams // Streams of the running undo command
+}
+
+func (r *jsonReporter) emit(event runEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event.Time = time.Now()
+	_ = r.encoder.Encode(event)
+}
+
+func (r *jsonReporter) runStarted(checkpoint *runCheckpoint, steps []*plannedStep) {
+	event := runEvent{
+		Event:    "run_start",
+		RunID:    checkpoint.ID,
+		Set:      checkpoint.Set,
+		Version:  checkpoint.Version,
+		Platform: checkpoint.Platform,
+		Steps:    []eventStep{},
+	}
+	for _, step := range steps {
+		event.Steps = append(event.Steps, eventStep{
+			Step:        step.num,
+			ID:          step.cmd.ID,
+			Description: step.cmd.Description,
+			DependsOn:   step.cmd.DependsOn,
+		})
+	}
+	r.emit(event)
+}
+
+func (r *jsonReporter) step(step *plannedStep, total int) stepReporter {
+	report := &jsonStepReporter{r: r, step: step, total: total}
+	report.out = stepStreams{
+		stdout: report.outputWriter("stdout"),
+		stderr: report.outputWriter("stderr"),
+	}
+	if !r.parallel {
+		report.out.stdin = os.Stdin
+	}
+	return report
+}
+
+func (r *jsonReporter) runFinished(checkpoint *runCheckpoint, failures []stepFailure, resumeFrom int) {
+	event := runEvent{
+		Event:      "run_end",
+		RunID:      checkpoint.ID,
+		Status:     stepSuccess,
+		DurationMS: durationMS(time.Since(r.started)),
+	}
+	if len(failures) > 0 {
+		event.Status = stepFailed
+		event.ResumeFrom = resumeFrom
+		for _, f := range failures {
+			event.FailedSteps = append(event.FailedSteps, f.num)
+		}
+	}
+	r.emit(event)
+}
+
+func (r *jsonReporter) rollbackStarted(plan []rollbackStep) {
+	r.emit(runEvent{Event: "rollback_start", Total: len(plan)})
+}
+
+func (r *jsonReporter) undoStarted(undo rollbackStep, position, total int) stepStreams {
+	r.emit(runEvent{Event: "undo_start", Step: undo.Step, Description: undo.Description, Command: undo.Shown, Order: position, Total: total})
+	report := &jsonStepReporter{r: r, step: &plannedStep{num: undo.Step}}
+	r.undoOut = stepStreams{stdout: report.outputWriter("stdout"), stderr: report.outputWriter("stderr")}
+	return r.undoOut
+}
+
+func (r *jsonReporter) undoFinished(undo rollbackStep, err error) {
+	r.undoOut.stdout.(*lineWriter).flush()
+	r.undoOut.stderr.(*lineWriter).flush()
+	event := runEvent{Event: "undo_end", Step: undo.Step, Status: stepSuccess, ExitCode: intPtr(exitCodeOf(err))}
+	if err != nil {
+		event.Status = stepFailed
+		event.Error = err.Error()
+	}
+	r.emit(event)
+}
+
+func (r *jsonReporter) rollbackFinished(err error) {
+	event := runEvent{Event: "rollback_end", Status: stepSuccess}
+	if err != nil {
+		event.Status = stepFailed
+		event.Error = err.Error()
+	}
+	r.emit(event)
+}
+
+// jsonStepReporter writes the events of one step
+type jsonStepReporter struct {
+	r     *jsonReporter
+	step  *plannedStep
+	total int
+	out   stepStreams
+}
+
+// outputWriter returns a writer that turns each line into an output event
+func (s *jsonStepReporter) outputWriter(stream string) *lineWriter {
+	return &lineWriter{emit: func(line []byte) {
+		text := string(line)
+		s.r.emit(runEvent{Event: "output", Step: s.step.num, ID: s.step.cmd.ID, Stream: stream, Line: &text})
+	}}
+}
+
+func (s *jsonStepReporter) streams() stepStreams {
+	return s.out
+}
+
+func (s *jsonStepReporter) started() {
+	s.r.emit(runEvent{
+		Event:       "step_start",
+		Step:        s.step.num,
+		ID:          s.step.cmd.ID,
+		Description: s.step.cmd.Description,
+		Order:       s.step.order,
+		Total:       s.total,
+	})
+}
+
+func (s *jsonStepReporter) command(command string) {
+	s.r.emit(runEvent{Event: "command", Step: s.step.num, ID: s.step.cmd.ID, Command: command})
+}
+
+func (s *jsonStepReporter) skipped(reason, detail string) {
+	status := stepSkipped
+	if reason == skipCheck {
+		status = stepSatisfied
+	}
+	s.r.emit(runEvent{Event: "step_skipped", Step: s.step.num, ID: s.step.cmd.ID, Status: status, Reason: reason, Detail: detail})
+}
+
+func (s *jsonStepReporter) retrying(attempt, attempts int, err error, delay time.Duration) {
+	s.close() // Output of the failed attempt comes first
+	s.r.emit(runEvent{
+		Event:    "retry",
+		Step:     s.step.num,
+		ID:       s.step.cmd.ID,
+		Attempt:  attempt,
+		Attempts: attempts,
+		Error:    err.Error(),
+		DelayMS:  durationMS(delay),
+	})
+}
+
+func (s *jsonStepReporter) finished(result stepResult, duration time.Duration, err error) {
+	s.end(result, duration, err, false)
+}
+
+func (s *jsonStepReporter) failed(result stepResult, duration time.Duration, err error) {
+	s.end(result, duration, err, true)
+}
+
+func (s *jsonStepReporter) end(result stepResult, duration time.Duration, err error, fatal bool) {
+	s.close() // Output comes before the end of the step
+	event := runEvent{
+		Event:      "step_end",
+		Step:       s.step.num,
+		ID:         s.step.cmd.ID,
+		Status:     result.Status,
+		ExitCode:   intPtr(result.ExitCode),
+		DurationMS: durationMS(duration),
+		Fatal:      fatal,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	s.r.emit(event)
+}
+
+func (s *jsonStepReporter) close() {
+	s.out.stdout.(*lineWriter).flush()
+	s.out.stderr.(*lineWriter).flush()
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func durationMS(d time.Duration) *int64 {
+	ms := d.Milliseconds()
+	return &ms
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+func TestJSONReporter(t *testing.T) {
+	var out bytes.Buffer
+	reporter := &jsonReporter{encoder: json.NewEncoder(&out), started: time.Now()}
+	checkpoint := &runCheckpoint{ID: "test-run", Set: "test", Version: "1.0.0", Platform: "linux"}
+	step := &plannedStep{cmd: repo.Command{ID: "build", Description: "Build"}, num: 2, order: 1}
+
+	reporter.runStarted(checkpoint, []*plannedStep{step})
+	report := reporter.step(step, 1)
+	report.started()
+	report.command("make")
+	fmt.Fprint(report.streams().stdout, "compiling\n\ndone")
+	fmt.Fprintln(report.streams().stderr, "warning")
+	report.failed(stepResult{Status: stepFailed, ExitCode: 2}, 1500*time.Millisecond, errors.New("exit status 2"))
+	reporter.runFinished(checkpoint, []stepFailure{{num: 2}}, 2)
+
+	var events []map[string]interface{}
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var event map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Invalid event %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+
+	expected := []struct {
+		event string
+		field string
+		value interface{}
+	}{
+		{"run_start", "run_id", "test-run"},
+		{"step_start", "id", "build"},
+		{"command", "command", "make"},
+		{"output", "line", "compiling"},
+		{"output", "line", ""},
+		{"output", "line", "warning"},
+		{"output", "line", "done"}, // Flushed when the step ends
+		{"step_end", "exit_code", float64(2)},
+		{"run_end", "status", "failed"},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d: %s", len(expected), len(events), out.String())
+	}
+	for i, e := range expected {
+		if events[i]["event"] != e.event || events[i][e.field] != e.value {
+			t.Errorf("Event %d: expected %s with %s=%v, got %v", i, e.event, e.field, e.value, events[i])
+		}
+	}
+	if events[7]["duration_ms"] != float64(1500) || events[7]["fatal"] != true {
+		t.Errorf("Expected step_end with duration and fatal, got %v", events[7])
+	}
+}
+
+func TestNewRunReporter(t *testing.T) {
+	for _, output := range []string{"", "text", "json"} {
+		if _, err := newRunReporter(output, false); err != nil {
+			t.Errorf("newRunReporter(%q) failed: %v", output, err)
+		}
+	}
+	if _, err := newRunReporter("yaml", false); err == nil {
+		t.Error("newRunReporter(\"yaml\") expected error, got nil")
+	}
+}
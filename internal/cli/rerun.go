@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/shelldock/shelldock/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rerunYesFlag               bool
+	rerunArgsFlag              string
+	rerunArgsFileFlag          []string
+	rerunRollbackOnFailureFlag bool
+	rerunJobsFlag              int
+	rerunOutputFlag            string
+	rerunStepFlag              bool
+	rerunEditFlag              bool
+)
+
+var rerunCmd = &cobra.Command{
+	Use:   "rerun [run-id]",
+	Short: "Run a past run again with the same version and arguments",
+	Long: `Run a command set again as recorded in the history: the same version,
+step selection and arguments. Redacted secret arguments are prompted for
+again, or can be given with --args.
+
+Examples:
+  shelldock rerun docker-20240101-120000
+  shelldock rerun myapp-20240101-120000 --args db_password=secret`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		r, err := loadRunRecord(args[0])
+		handleError(err)
+
+		manager, err := repo.NewManager()
+		handleError(err)
+
+		cmdSet, err := manager.GetCommandSet(r.Set, r.Local, r.Version)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		redacted := make(map[string]bool)
+		for _, name := range r.Redacted {
+			redacted[name] = true
+		}
+		values := make(map[string]string)
+		for name, value := range r.Args {
+			if !redacted[name] {
+				values[name] = value
+			}
+		}
+
+		// Secrets were not recorded and have to be given again
+		prompted, err := unrecordedArgs(cmdSet, r.Redacted)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(prompted) > 0 {
+			fmt.Fprintf(os.Stderr, "🔒 Not recorded, will be prompted for: %s\n", strings.Join(prompted, ", "))
+		}
+
+		executeCommandSet(cmdSet, runOptions{
+			SkipSteps: r.SkipSteps,
+			OnlySteps: r.OnlySteps,
+			FromStep:  r.FromStep,
+			ToStep:    r.ToStep,
+			Yes:       rerunYesFlag,
+			Args:      rerunArgsFlag,
+			ArgsFiles: rerunArgsFileFlag,
+			Values:    values,
+			Local:     r.Local,
+			Jobs:      rerunJobsFlag,
+			Output:    rerunOutputFlag,
+			Step:      rerunStepFlag,
+			Edit:      rerunEditFlag,
+			Reenter:   prompted,
+
+			RollbackOnFailure: rerunRollbackOnFailureFlag,
+		})
+	},
+}
+
+func init() {
+	rerunCmd.Flags().BoolVarP(&rerunYesFlag, "yes", "y", false, "Execute commands without prompting for confirmation")
+	rerunCmd.Flags().StringVar(&rerunArgsFlag, "args", "", "Override recorded arguments as key=value pairs (e.g., --args name=John)")
+	rerunCmd.Flags().StringArrayVar(&rerunArgsFileFlag, "args-file", nil, "Override recorded arguments from a YAML, JSON or .env file (can be repeated)")
+	rerunCmd.Flags().IntVarP(&rerunJobsFlag, "jobs", "j", defaultJobs, "Maximum number of steps to run at the same time when steps declare depends_on")
+	rerunCmd.Flags().StringVarP(&rerunOutputFlag, "output", "o", outputText, "Output format: text, or json for newline-delimited JSON events (requires --yes)")
+	rerunCmd.Flags().BoolVar(&rerunRollbackOnFailureFlag, "rollback-on-failure", false, "Run the undo commands of completed steps in reverse order if a step fails")
+	rerunCmd.Flags().BoolVar(&rerunEditFlag, "edit", false, "Edit the rendered commands in $EDITOR before running them")
+	rerunCmd.Flags().BoolVar(&rerunStepFlag, "step", false, "Ask before each step whether to run, skip or edit it, and after a failure whether to retry, skip or debug it")
+}
+
+// unrecordedArgs checks that the arguments whose values were not recorded can
+// be given again: with --args, --args-file or the environment, fetched from
+// their source, or entered in the terminal. It returns those that will be
+// prompted for, even if they have a default, so a recorded value is never
+// replaced by the default without asking.
+func unrecordedArgs(cmdSet *repo.CommandSet, names []string) ([]string, error) {
+	commands := withSharedArgs(cmdSet)
+	provided, err := loadArgsFiles(rerunArgsFileFlag)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range envArgValues(commands) {
+		provided[name] = value
+	}
+	for name, value := range parseArgsFlag(rerunArgsFlag) {
+		provided[name] = value
+	}
+	declared := make(map[string]bool)
+	fetched := make(map[string]bool)
+	for _, cmd := range commands {
+		for _, argDef := range cmd.Args {
+			declared[argDef.Name] = true
+			if argDef.From != nil {
+				fetched[argDef.Name] = true
+			}
+		}
+	}
+
+	var prompted []string
+	for _, name := range names {
+		if _, given := provided[name]; given || fetched[name] || !declared[name] {
+			continue
+		}
+		prompted = append(prompted, name)
+	}
+	if len(prompted) > 0 && !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, fmt.Errorf("arguments not recorded in the history: %s (not in a terminal, use --args, --args-file or SHELLDOCK_ARG_<NAME>)", strings.Join(prompted, ", "))
+	}
+	return prompted, nil
+}
@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+
+	"github.com/shelldock/shelldock/internal/repo"
+	"github.com/spf13/cobra"
+)
+
+var (
+	resumeYesFlag               bool
+	resumeRollbackFlag          bool
+	resumeRollbackOnFailureFlag bool
+	resumeJobsFlag              int
+	resumeOutputFlag            string
+)
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume [run-id]",
+	Short: "Resume a failed run from the step that failed",
+	Long: `Resume a failed run from the step that failed, reusing the version,
+platform, step selection and arguments of the original run.
+
+Without a run ID, lists the runs that can be resumed.
+
+With --rollback, runs the undo commands of the run's completed steps
+in reverse order instead of resuming it.
+
+Examples:
+  shelldock resume                                    # List resumable runs
+  shelldock resume docker-20240101-120000             # Resume a specific run
+  shelldock resume docker-20240101-120000 --rollback  # Undo its completed steps`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			checkpoints, err := listCheckpoints()
+			handleError(err)
+
+			if len(checkpoints) == 0 {
+				fmt.Println("No runs to resume.")
+				return
+			}
+
+			fmt.Println("Resumable runs:")
+			fmt.Println()
+			for _, c := range checkpoints {
+				status := fmt.Sprintf("%d step(s) completed", len(c.CompletedSteps))
+				if c.FailedStep > 0 {
+					status = fmt.Sprintf("failed at step %d", c.FailedStep)
+				}
+				fmt.Printf("  • %s (%s@%s, %s, %s)\n", c.ID, c.Set, c.Version, status, c.UpdatedAt.Format("2006-01-02 15:04:05"))
+			}
+			fmt.Println()
+			fmt.Println("💡 Resume with: shelldock resume <run-id>")
+			return
+		}
+
+		checkpoint, err := loadCheckpoint(args[0])
+		handleError(err)
+
+		manager, err := repo.NewManager()
+		handleError(err)
+
+		cmdSet, err := manager.GetCommandSet(checkpoint.Set, checkpoint.Local, checkpoint.Version)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if resumeRollbackFlag {
+			rollbackRun(cmdSet, checkpoint)
+			return
+		}
+
+		executeCommandSet(cmdSet, runOptions{
+			Yes:    resumeYesFlag,
+			Local:  checkpoint.Local,
+			Resume: checkpoint,
+			Jobs:   resumeJobsFlag,
+			Output: resumeOutputFlag,
+
+			RollbackOnFailure: resumeRollbackOnFailureFlag,
+		})
+	},
+}
+
+func init() {
+	resumeCmd.Flags().BoolVarP(&resumeYesFlag, "yes", "y", false, "Execute remaining commands without prompting for confirmation")
+	resumeCmd.Flags().BoolVar(&resumeRollbackFlag, "rollback", false, "Run the undo commands of the run's completed steps instead of resuming it")
+	resumeCmd.Flags().IntVarP(&resumeJobsFlag, "jobs", "j", defaultJobs, "Maximum number of steps to run at the same time when steps declare depends_on")
+	resumeCmd.Flags().StringVarP(&resumeOutputFlag, "output", "o", outputText, "Output format: text, or json for newline-delimited JSON events (requires --yes)")
+	resumeCmd.Flags().BoolVar(&resumeRollbackOnFailureFlag, "rollback-on-failure", false, "Run the undo commands of completed steps in reverse order if a step fails")
+}
+
+// rollbackRun runs the undo commands of a failed run's completed steps. Each
+// undo command runs in a fresh shell, also for persistent session command sets.
+func rollbackRun(cmdSet *repo.CommandSet, checkpoint *runCheckpoint) {
+	commands := withShells(withEnvDefaults(withSharedArgs(cmdSet)), setShell(cmdSet))
+
+	// Secrets are not kept with the run's state, so the ones the undo commands
+	// need are resolved again
+	var undone []repo.Command
+	for _, step := range checkpoint.CompletedSteps {
+		if step >= 1 && step <= len(commands) && getUndoForPlatform(commands[step-1], checkpoint.Platform) != "" {
+			undone = append(undone, commands[step-1])
+		}
+	}
+	args, err := collectCommandArgs(undone, checkpoint.Args, builtinFacts(checkpoint.Platform, ""))
+	if err == nil {
+		checkpoint.setArgs(undone, args)
+		err = exportSecrets(commands, checkpoint.Args)
+	}
+	var plan []rollbackStep
+	if err == nil {
+		plan, err = planRollback(commands, checkpoint, checkpoint.Platform, quotesArgs(cmdSet))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(plan) == 0 {
+		fmt.Printf("Nothing to roll back for run %s.\n", checkpoint.ID)
+		return
+	}
+
+	printRollbackPlan(plan)
+	if !resumeYesFlag {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			fmt.Println("⚠️  Not running in a terminal. Use --yes flag to roll back without prompt.")
+			return
+		}
+		if !confirm("Do you want to roll back these steps?") {
+			fmt.Println("Cancelled.")
+			return
+		}
+	}
+
+	if err := runRollback(plan, checkpoint, processRunner{}, &textReporter{}); err != nil {
+		os.Exit(1)
+	}
+	fmt.Printf("💡 Run the remaining steps again with: shelldock resume %s\n", checkpoint.ID)
+}
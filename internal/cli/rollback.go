@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/shelldock/shelldock/internal/render"
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+// rollbackStep is the undo command of a completed step
+type rollbackStep struct {
+	Step        int // Step number (1-indexed) in the command set
+	Description string
+	Command     string
+	Shown       string // Command as shown, with the values of secrets masked
+	shell       shellSpec
+}
+
+// getUndoForPlatform returns the undo command for the specified platform
+// Returns empty string if the step has no undo
+func getUndoForPlatform(cmd repo.Command, platform string) string {
+	if cmd.UndoPlatforms != nil {
+		if platformUndo, exists := cmd.UndoPlatforms[platform]; exists {
+			return platformUndo
+		}
+	}
+	return cmd.Undo
+}
+
+// planRollback returns the undo commands of the steps that completed successfully,
+// most recently completed first. Skipped, satisfied and failed steps changed
+// nothing that needs to be undone.
+func planRollback(commands []repo.Command, checkpoint *runCheckpoint, platform string, quote bool) ([]rollbackStep, error) {
+	runDir, _ := checkpoint.dir()
+	facts := builtinFacts(platform, runDir)
+
+	var plan []rollbackStep
+	for i := len(checkpoint.CompletedSteps) - 1; i >= 0; i-- {
+		step := checkpoint.CompletedSteps[i]
+		if step < 1 || step > len(commands) || checkpoint.Results[step].Status != stepSuccess {
+			continue
+		}
+		cmd := commands[step-1]
+		undo := getUndoForPlatform(cmd, platform)
+		if undo == "" {
+			continue
+		}
+		values := templateValues(cmd, facts, checkpoint.Vars, checkpoint.Args)
+		command, err := render.Render(undo, values, quote)
+		var shown string
+		if err == nil {
+			shown, err = render.Render(undo, redactValues(values), quote)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("step %d (%s): %w", step, cmd.Description, err)
+		}
+		plan = append(plan, rollbackStep{
+			Step:        step,
+			Description: cmd.Description,
+			Command:     command,
+			Shown:       shown,
+			shell:       commandShell(cmd),
+		})
+	}
+	return plan, nil
+}
+
+// printRollbackPlan lists the undo commands that a rollback would run
+func printRollbackPlan(plan []rollbackStep) {
+	fmt.Printf("↩️  Undo commands of %d completed step(s):\n\n", len(plan))
+	for _, r := range plan {
+		fmt.Printf("  %d. %s\n", r.Step, r.Description)
+		fmt.Printf("     $ %s\n", r.Shown)
+	}
+	fmt.Println()
+}
+
+// runRollback runs undo commands in order and stops at the first one that fails.
+// Undone steps are removed from the checkpoint, so resuming the run executes them again.
+func runRollback(plan []rollbackStep, checkpoint *runCheckpoint, runner commandRunner, reporter runReporter) error {
+	reporter.rollbackStarted(plan)
+
+	for i, r := range plan {
+		streams := reporter.undoStarted(r, i+1, len(plan))
+		err := runner.withShell(r.shell).run(r.Command, 0, streams)
+		reporter.undoFinished(r, err)
+		if err != nil {
+			err = fmt.Errorf("undo of step %d failed: %w", r.Step, err)
+			reporter.rollbackFinished(err)
+			return err
+		}
+		saveCheckpoint(checkpoint.unmarkCompleted(r.Step))
+	}
+
+	reporter.rollbackFinished(nil)
+	return nil
+}
+
+// offerRollback rolls back the completed steps of a failed run, automatically
+// with --rollback-on-failure or after confirmation in a terminal. It returns the
+// lowest step that was undone, or 0 if nothing was.
+func offerRollback(commands []repo.Command, checkpoint *runCheckpoint, platform string, quote bool, runner commandRunner, reporter runReporter, opts runOptions) int {
+	plan, err := planRollback(commands, checkpoint, platform, quote)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n⚠️  Cannot roll back: %v\n", err)
+		return 0
+	}
+	if len(plan) == 0 {
+		return 0
+	}
+
+	if !opts.RollbackOnFailure {
+		if opts.Yes || !term.IsTerminal(int(os.Stdin.Fd())) {
+			fmt.Fprintf(os.Stderr, "\n↩️  %d completed step(s) can be rolled back with: shelldock resume %s --rollback\n", len(plan), checkpoint.ID)
+			return 0
+		}
+		fmt.Println()
+		printRollbackPlan(plan)
+		if !confirm("Do you want to roll back these steps?") {
+			return 0
+		}
+	}
+
+	_ = runRollback(plan, checkpoint, runner, reporter) // Reported as it runs
+
+	lowest := 0
+	for _, r := range plan {
+		if !checkpoint.isCompleted(r.Step) && (lowest == 0 || r.Step < lowest) {
+			lowest = r.Step
+		}
+	}
+	return lowest
+}
+
+// confirm asks a yes/no question in the terminal, defaulting to no
+func confirm(question string) bool {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Printf("%s (y/N): ", question)
+	_ = os.Stdout.Sync()
+
+	response, err := reader.ReadString('\n')
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nError reading input: %v\n", err)
+		return false
+	}
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "y" || response == "yes"
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"os"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+func TestPlanRollback(t *testing.T) {
+	commands := []repo.Command{
+		{Description: "Install", Command: "apt-get install -y ufw"},
+		{Description: "Set policy", Command: "ufw default deny incoming", Undo: "ufw default allow incoming"},
+		{Description: "Allow port", Command: "ufw allow {{port}}", Undo: "ufw delete allow {{port}}"},
+		{Description: "Already enabled", Command: "ufw enable", Undo: "ufw disable"},
+		{Description: "Enable logging", Command: "ufw logging on", Undo: "ufw logging off",
+			UndoPlatforms: map[string]string{"ubuntu": "ufw logging low"}},
+	}
+
+	checkpoint := &runCheckpoint{
+		Args:           map[string]string{"port": "8080"},
+		CompletedSteps: []int{1, 2, 3, 4, 5},
+		Results: map[int]stepResult{
+			1: {Status: stepSuccess},
+			2: {Status: stepSuccess},
+			3: {Status: stepSuccess},
+			4: {Status: stepSatisfied},
+			5: {Status: stepSuccess},
+		},
+	}
+
+	plan, err := planRollback(commands, checkpoint, "ubuntu", false)
+	if err != nil {
+		t.Fatalf("planRollback failed: %v", err)
+	}
+
+	expected := []rollbackStep{
+		{Step: 5, Description: "Enable logging", Command: "ufw logging low", Shown: "ufw logging low"},
+		{Step: 3, Description: "Allow port", Command: "ufw delete allow 8080", Shown: "ufw delete allow 8080"},
+		{Step: 2, Description: "Set policy", Command: "ufw default allow incoming", Shown: "ufw default allow incoming"},
+	}
+	if len(plan) != len(expected) {
+		t.Fatalf("Expected %d undo commands, got %d: %+v", len(expected), len(plan), plan)
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(plan[i], expected[i]) {
+			t.Errorf("plan[%d] = %+v, expected %+v", i, plan[i], expected[i])
+		}
+	}
+
+	// Sets that quote arguments quote them in undo commands too
+	if plan, _ := planRollback(commands, checkpoint, "ubuntu", true); plan[1].Command != "ufw delete allow '8080'" {
+		t.Errorf("Expected a quoted port, got %q", plan[1].Command)
+	}
+
+	// Secrets are passed through the environment and masked when shown
+	commands[2].Args = []repo.ArgumentDef{{Name: "port", Secret: true}}
+	plan, _ = planRollback(commands, checkpoint, "ubuntu", false)
+	if plan[1].Command != `ufw delete allow "${SHELLDOCK_SECRET_PORT}"` || plan[1].Shown != "ufw delete allow ****" {
+		t.Errorf("Expected the secret to be referenced and masked, got %+v", plan[1])
+	}
+	commands[2].Args = nil
+
+	// An undo command referring to an unknown value can't be planned
+	delete(checkpoint.Args, "port")
+	if _, err := planRollback(commands, checkpoint, "ubuntu", false); err == nil {
+		t.Error("Expected an error for an unresolved placeholder")
+	}
+}
+
+func TestRunRollback(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell commands")
+	}
+
+	tmpDir := t.TempDir()
+
+	// Temporarily override HOME
+	originalHome := os.Getenv("HOME")
+	defer func() {
+		if originalHome != "" {
+			_ = os.Setenv("HOME", originalHome)
+		}
+	}()
+
+	_ = os.Setenv("HOME", tmpDir)
+
+	checkpoint, err := newRunCheckpoint("ufw", "v1", "ubuntu")
+	if err != nil {
+		t.Fatalf("newRunCheckpoint failed: %v", err)
+	}
+	for step := 1; step <= 3; step++ {
+		if err := checkpoint.markCompleted(step, stepResult{Status: stepSuccess}); err != nil {
+			t.Fatalf("markCompleted failed: %v", err)
+		}
+	}
+
+	// Rollback stops at the first failing undo command
+	plan := []rollbackStep{
+		{Step: 3, Command: "true"},
+		{Step: 2, Command: "exit 1"},
+		{Step: 1, Command: "true"},
+	}
+	if err := runRollback(plan, checkpoint, processRunner{}, &textReporter{}); err == nil {
+		t.Error("Expected rollback to fail")
+	}
+
+	loaded, err := loadCheckpoint(checkpoint.ID)
+	if err != nil {
+		t.Fatalf("loadCheckpoint failed: %v", err)
+	}
+	if loaded.isCompleted(3) {
+		t.Error("Expected undone step 3 to be removed from the checkpoint")
+	}
+	if !loaded.isCompleted(2) || !loaded.isCompleted(1) {
+		t.Errorf("Expected steps 1 and 2 to remain completed, got %v", loaded.CompletedSteps)
+	}
+}
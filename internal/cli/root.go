@@ -13,9 +13,19 @@ var (
 	rootLocalFlag bool
 	rootSkipSteps string
 	rootOnlySteps string
+	rootFromStep string
+	rootToStep string
 	rootVersionFlag string
 	rootYesFlag bool
 	rootArgsFlag string
+	rootArgsFileFlag []string
+	rootResumeFlag bool
+	rootRollbackOnFailureFlag bool
+	rootJobsFlag int
+	rootOutputFlag string
+	rootStepFlag bool
+	rootEditFlag bool
+	rootCheckFlag bool
 )
 
 var rootCmd = &cobra.Command{
@@ -48,16 +58,43 @@ Or use subcommands:
 				version = rootVersionFlag
 			}
 			
+			opts := runOptions{
+				SkipSteps: rootSkipSteps,
+				OnlySteps: rootOnlySteps,
+				FromStep:  rootFromStep,
+				ToStep:    rootToStep,
+				Yes:       rootYesFlag,
+				Args:      rootArgsFlag,
+				ArgsFiles: rootArgsFileFlag,
+				Local:     rootLocalFlag,
+				Jobs:      rootJobsFlag,
+				Output:    rootOutputFlag,
+				Step:      rootStepFlag,
+				Edit:      rootEditFlag,
+				Check:     rootCheckFlag,
+
+				RollbackOnFailure: rootRollbackOnFailureFlag,
+			}
+
+			// Continue the most recent failed run of this command set
+			if rootResumeFlag {
+				checkpoint, err := findLatestCheckpoint(name)
+				handleError(err)
+				version = checkpoint.Version
+				opts.Local = checkpoint.Local
+				opts.Resume = checkpoint
+			}
+			
 			manager, err := repo.NewManager()
 			handleError(err)
 
-			cmdSet, err := manager.GetCommandSet(name, rootLocalFlag, version)
+			cmdSet, err := manager.GetCommandSet(name, opts.Local, version)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
 
-			executeCommandSet(cmdSet, rootSkipSteps, rootOnlySteps, rootYesFlag, rootArgsFlag)
+			executeCommandSet(cmdSet, opts)
 			return
 		}
 		// Otherwise show help
@@ -73,12 +110,25 @@ func Execute(version string) error {
 
 func init() {
 	rootCmd.Flags().BoolVarP(&rootLocalFlag, "local", "l", false, "Only check local repository (skip bundled repository)")
-	rootCmd.Flags().StringVar(&rootSkipSteps, "skip", "", "Skip specific steps (comma-separated or range, e.g., 1,2,3 or 1-3)")
-	rootCmd.Flags().StringVar(&rootOnlySteps, "only", "", "Run only specific steps (comma-separated or range, e.g., 1,3,5 or 1-3)")
+	rootCmd.Flags().StringVar(&rootSkipSteps, "skip", "", "Skip specific steps by number, range, id or tag (e.g., 1,2,3, 1-3, 5-, install or tag:verify)")
+	rootCmd.Flags().StringVar(&rootOnlySteps, "only", "", "Run only specific steps by number, range, id or tag (e.g., 1,3,5, 1-3, 5-, install or tag:verify)")
+	rootCmd.Flags().StringVar(&rootFromStep, "from", "", "Start at a step, given by number or id")
+	rootCmd.Flags().StringVar(&rootToStep, "to", "", "Stop after a step, given by number or id")
 	rootCmd.Flags().StringVar(&rootVersionFlag, "ver", "", "Run specific version or tag (default: latest). Can also use name@version format")
 	rootCmd.Flags().BoolVarP(&rootYesFlag, "yes", "y", false, "Execute commands without prompting for confirmation")
 	rootCmd.Flags().StringVar(&rootArgsFlag, "args", "", "Provide arguments as key=value pairs (e.g., --args name=John,email=john@example.com)")
+	rootCmd.Flags().StringArrayVar(&rootArgsFileFlag, "args-file", nil, "Read arguments from a YAML, JSON or .env file (can be repeated, later files win)")
+	rootCmd.Flags().BoolVar(&rootResumeFlag, "resume", false, "Resume the last failed run of this command set from the failed step")
+	rootCmd.Flags().IntVarP(&rootJobsFlag, "jobs", "j", defaultJobs, "Maximum number of steps to run at the same time when steps declare depends_on")
+	rootCmd.Flags().StringVarP(&rootOutputFlag, "output", "o", outputText, "Output format: text, or json for newline-delimited JSON events (requires --yes)")
+	rootCmd.Flags().BoolVar(&rootRollbackOnFailureFlag, "rollback-on-failure", false, "Run the undo commands of completed steps in reverse order if a step fails")
+	rootCmd.Flags().BoolVar(&rootEditFlag, "edit", false, "Edit the rendered commands in $EDITOR before running them")
+	rootCmd.Flags().BoolVar(&rootCheckFlag, "check", false, "Run check commands in the preview to show which steps are already satisfied")
+	rootCmd.Flags().BoolVar(&rootStepFlag, "step", false, "Ask before each step whether to run, skip or edit it, and after a failure whether to retry, skip or debug it")
 	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(resumeCmd)
+	rootCmd.AddCommand(rerunCmd)
+	rootCmd.AddCommand(historyCmd)
 	rootCmd.AddCommand(showCmd)
 	rootCmd.AddCommand(echoCmd)
 	rootCmd.AddCommand(manageCmd)
@@ -2,28 +2,67 @@ package cli
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
 	"golang.org/x/term"
 
 	"github.com/shelldock/shelldock/internal/config"
+	"github.com/shelldock/shelldock/internal/render"
 	"github.com/shelldock/shelldock/internal/repo"
 	"github.com/spf13/cobra"
 )
 
+// varNamePattern matches valid variable names for registered step output
+var varNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 var (
 	localFlag bool
 	skipSteps string
 	onlySteps string
+	fromStep string
+	toStep string
 	versionFlag string
 	yesFlag bool
 	argsFlag string
+	argsFileFlag []string
+	resumeFlag bool
+	rollbackOnFailureFlag bool
+	jobsFlag int
+	outputFlag string
+	stepFlag bool
+	editFlag bool
+	checkFlag bool
 )
 
+// runOptions holds the options controlling how a command set is executed
+type runOptions struct {
+	SkipSteps string
+	OnlySteps string
+	FromStep  string // First step to run, by number or id
+	ToStep    string // Last step to run, by number or id
+	Yes       bool
+	Args      string
+	ArgsFiles []string          // Files with argument values, overridden by Args
+	Values    map[string]string // Argument values of an earlier run, overridden by ArgsFiles and Args
+	Local     bool
+	Resume    *runCheckpoint // Checkpoint of a failed run to continue from, if any
+	Jobs      int            // Maximum number of steps running at the same time (0 for the default)
+	Output    string         // Output format, text or json
+	Step      bool           // Ask before each step and after each failure what to do
+	Edit      bool           // Edit the rendered commands before running them
+	Check     bool           // Run the check commands in the preview to show which steps are already satisfied
+	Reenter   []string       // Arguments whose values were not kept, prompted for even without a prompt
+
+	RollbackOnFailure bool // Run the undo commands of completed steps without asking when a step fails
+}
+
 // parseStepNumbers parses comma-separated step numbers (1-indexed)
 func parseStepNumbers(input string) (map[int]bool, error) {
 	if input == "" {
@@ -135,10 +174,24 @@ func getCommandForPlatform(cmd repo.Command, platform string) string {
 		}
 	}
 
-	// Fallback to generic command
+	// Fallback to generic command, or the script
+	if cmd.Script != "" {
+		return cmd.Script
+	}
 	return cmd.Command
 }
 
+// getCheckForPlatform returns the check command for the specified platform
+// Returns empty string if the step has no check
+func getCheckForPlatform(cmd repo.Command, platform string) string {
+	if cmd.CheckPlatforms != nil {
+		if platformCheck, exists := cmd.CheckPlatforms[platform]; exists {
+			return platformCheck
+		}
+	}
+	return cmd.Check
+}
+
 // parseArgsFlag parses the --args flag value (format: key1=value1,key2=value2)
 func parseArgsFlag(argsStr string) map[string]string {
 	args := make(map[string]string)
@@ -165,6 +218,31 @@ func parseArgsFlag(argsStr string) map[string]string {
 	return args
 }
 
+// withPrompts returns the commands with a prompt for each of the named
+// arguments that has none, so they are asked for instead of taking their
+// default
+func withPrompts(commands []repo.Command, names []string) []repo.Command {
+	if len(names) == 0 {
+		return commands
+	}
+	reenter := make(map[string]bool)
+	for _, name := range names {
+		reenter[name] = true
+	}
+
+	result := make([]repo.Command, len(commands))
+	for i, cmd := range commands {
+		cmd.Args = append([]repo.ArgumentDef(nil), cmd.Args...)
+		for j, argDef := range cmd.Args {
+			if reenter[argDef.Name] && argDef.Prompt == "" {
+				cmd.Args[j].Prompt = fmt.Sprintf("Enter %s", argDef.Name)
+			}
+		}
+		result[i] = cmd
+	}
+	return result
+}
+
 // promptForArg prompts the user for an argument value
 func promptForArg(argDef repo.ArgumentDef, providedArgs map[string]string) string {
 	// Check if already provided
@@ -203,9 +281,15 @@ func promptForArg(argDef repo.ArgumentDef, providedArgs map[string]string) strin
 		prompt = fmt.Sprintf("Enter %s", argDef.Name)
 	}
 	
+	if len(argDef.Choices) > 0 {
+		return selectArgChoice(argDef, prompt, reader)
+	}
+	
 	// Build the prompt message with default hint
 	promptMsg := prompt
-	if argDef.Default != "" {
+	if argDef.Default != "" && argDef.IsSecret() {
+		promptMsg = fmt.Sprintf("%s [default: %s]", prompt, redactedValue)
+	} else if argDef.Default != "" {
 		promptMsg = fmt.Sprintf("%s [default: %s]", prompt, argDef.Default)
 	} else if !argDef.Required {
 		promptMsg = fmt.Sprintf("%s (optional)", prompt)
@@ -218,10 +302,20 @@ func promptForArg(argDef repo.ArgumentDef, providedArgs map[string]string) strin
 		promptMsg = promptMsg + " "
 	}
 	
-	fmt.Print(promptMsg)
-	_ = os.Stdout.Sync()
+	// Prompts go to stderr so they don't mix with JSON output
+	fmt.Fprint(os.Stderr, promptMsg)
 	
-	response, err := reader.ReadString('\n')
+	var response string
+	var err error
+	if argDef.IsSecret() {
+		// Secrets are read without echo
+		var secret []byte
+		secret, err = term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		response = string(secret)
+	} else {
+		response, err = reader.ReadString('\n')
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "\nError reading input: %v\n", err)
 		// On error, use default if available
@@ -248,52 +342,250 @@ func promptForArg(argDef repo.ArgumentDef, providedArgs map[string]string) strin
 	return value
 }
 
-// collectCommandArgs collects all arguments for a command
-func collectCommandArgs(cmd repo.Command, providedArgs map[string]string) map[string]string {
+// selectArgChoice prompts for an argument with choices using a numbered menu.
+// The choice can be entered by number or by value, and an empty answer selects
+// the default.
+func selectArgChoice(argDef repo.ArgumentDef, prompt string, reader *bufio.Reader) string {
+	fmt.Fprintf(os.Stderr, "%s:\n", strings.TrimRight(prompt, ":? "))
+	for i, choice := range argDef.Choices {
+		if choice == argDef.Default {
+			fmt.Fprintf(os.Stderr, "  %d) %s (default)\n", i+1, choice)
+		} else {
+			fmt.Fprintf(os.Stderr, "  %d) %s\n", i+1, choice)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Select [1-%d]: ", len(argDef.Choices))
+
+	response, err := reader.ReadString('\n')
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nError reading input: %v\n", err)
+		return argDef.Default
+	}
+
+	value := strings.TrimSpace(response)
+	if value == "" {
+		return argDef.Default
+	}
+	if n, err := strconv.Atoi(value); err == nil && n >= 1 && n <= len(argDef.Choices) {
+		return argDef.Choices[n-1]
+	}
+	return value
+}
+
+// collectCommandArgs resolves the arguments of all commands before anything runs.
+// Arguments with a source are fetched from it, computed defaults are computed
+// from the built-in facts and the arguments they depend on, and an argument
+// used by several commands is prompted for once. It returns the
+// values that were provided or entered; commands fall back to their own
+// defaults for the others (see stepArgs). Every missing required argument is
+// reported in one error.
+func collectCommandArgs(commands []repo.Command, providedArgs, facts map[string]string) (map[string]string, error) {
+	// Merge the definitions of arguments used by several commands
+	var defs []repo.ArgumentDef
+	var shells []shellSpec // Shell running the source command or default_from of each argument
+	positions := make(map[string]int)
+	for _, cmd := range commands {
+		for _, argDef := range cmd.Args {
+			pos, exists := positions[argDef.Name]
+			if !exists {
+				positions[argDef.Name] = len(defs)
+				defs = append(defs, argDef)
+				shells = append(shells, commandShell(cmd))
+				continue
+			}
+			if argDef.Required {
+				defs[pos].Required = true
+			}
+			if defs[pos].Prompt == "" {
+				defs[pos].Prompt = argDef.Prompt
+			}
+			if defs[pos].Default == "" && defs[pos].DefaultFrom == "" {
+				defs[pos].Default = argDef.Default
+				defs[pos].DefaultFrom = argDef.DefaultFrom
+			}
+			if defs[pos].Env == "" {
+				defs[pos].Env = argDef.Env
+			}
+			if argDef.Secret {
+				defs[pos].Secret = true
+			}
+			if defs[pos].From == nil {
+				defs[pos].From = argDef.From
+			}
+			if defs[pos].Type == "" && len(defs[pos].Choices) == 0 && defs[pos].Pattern == "" {
+				defs[pos].Type = argDef.Type
+				defs[pos].Choices = argDef.Choices
+				defs[pos].Pattern = argDef.Pattern
+				defs[pos].Min = argDef.Min
+				defs[pos].Max = argDef.Max
+				defs[pos].Error = argDef.Error
+			}
+		}
+	}
+
+	// Defaults computed from other arguments are resolved after those
+	order, err := defaultOrder(defs)
+	if err != nil {
+		return nil, err
+	}
+
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
 	result := make(map[string]string)
-	
-	for _, argDef := range cmd.Args {
-		value := promptForArg(argDef, providedArgs)
-		if value == "" && argDef.Required {
-			fmt.Fprintf(os.Stderr, "Error: Required argument '%s' is missing\n", argDef.Name)
-			os.Exit(1)
+	known := make(map[string]string) // Values or defaults of the arguments resolved so far
+	for _, pos := range order {
+		argDef := defs[pos]
+		if value, exists := providedArgs[argDef.Name]; exists {
+			result[argDef.Name] = value
+			known[argDef.Name] = value
+			continue
+		}
+		if argDef.From != nil {
+			value, err := resolveArgSource(*argDef.From, shells[pos])
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch %s: %w", argDef.Name, err)
+			}
+			result[argDef.Name] = value
+			known[argDef.Name] = value
+			continue
+		}
+		if argDef.HasComputedDefault() {
+			value, err := computeDefault(argDef, shells[pos], facts, known)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compute the default of %s: %w", argDef.Name, err)
+			}
+			// A computed default is the same for every command
+			argDef.Default = value
+			result[argDef.Name] = value
 		}
-		if value != "" {
+		known[argDef.Name] = argDef.Default
+		if !interactive {
+			continue
+		}
+		// Accepting a static default leaves each command with its own default
+		for {
+			value := promptForArg(argDef, providedArgs)
+			if value == "" || value == argDef.Default {
+				break
+			}
+			if err := argDef.Validate(value); err != nil {
+				fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+				continue
+			}
 			result[argDef.Name] = value
+			known[argDef.Name] = value
+			break
 		}
 	}
-	
-	return result
+
+	var missing, invalid []string
+	reported := make(map[string]bool)
+	for _, cmd := range commands {
+		args := stepArgs(cmd, result)
+		for _, argDef := range cmd.Args {
+			if reported[argDef.Name] {
+				continue
+			}
+			if argDef.Required && args[argDef.Name] == "" {
+				missing = append(missing, argDef.Name)
+				reported[argDef.Name] = true
+			} else if err := argDef.Validate(args[argDef.Name]); err != nil {
+				invalid = append(invalid, err.Error())
+				reported[argDef.Name] = true
+			}
+		}
+	}
+
+	if len(invalid) > 0 {
+		return nil, errors.New(strings.Join(invalid, "; "))
+	}
+
+	if len(missing) > 0 {
+		if !interactive {
+			return nil, fmt.Errorf("missing required arguments: %s (not in a terminal, use --args, --args-file or SHELLDOCK_ARG_<NAME>)", strings.Join(missing, ", "))
+		}
+		return nil, fmt.Errorf("missing required arguments: %s", strings.Join(missing, ", "))
+	}
+	return result, nil
 }
 
-// substituteArgs replaces {{argName}} placeholders in command string with actual values
-func substituteArgs(command string, args map[string]string) string {
-	result := command
-	
-	for key, value := range args {
-		placeholder := fmt.Sprintf("{{%s}}", key)
-		result = strings.ReplaceAll(result, placeholder, value)
+// stepArgs returns the values of a command's arguments, using the command's
+// static defaults for arguments without a value
+func stepArgs(cmd repo.Command, values map[string]string) map[string]string {
+	args := make(map[string]string)
+	for _, argDef := range cmd.Args {
+		if value, exists := values[argDef.Name]; exists && value != "" {
+			args[argDef.Name] = value
+		} else if def := staticDefault(argDef); def != "" {
+			args[argDef.Name] = def
+		}
+	}
+	return args
+}
+
+// mergeValues combines value maps, later maps overriding earlier ones
+func mergeValues(maps ...map[string]string) map[string]string {
+	result := make(map[string]string)
+	for _, m := range maps {
+		for k, v := range m {
+			result[k] = v
+		}
 	}
-	
 	return result
 }
 
+// quotesArgs reports whether a command set shell-quotes substituted values by default
+func quotesArgs(cmdSet *repo.CommandSet) bool {
+	return cmdSet.QuoteArgs != nil && *cmdSet.QuoteArgs
+}
+
 // executeCommandSet is the shared logic for running command sets
-func executeCommandSet(cmdSet *repo.CommandSet, skipSteps, onlySteps string, yesFlag bool, argsFlag string) {
+func executeCommandSet(cmdSet *repo.CommandSet, opts runOptions) {
+	// Steps get the shared arguments of the set they use, with defaults taken
+	// from the environment where the arguments name a variable, and the
+	// set's shell unless they name their own
+	original := cmdSet
+	shell := setShell(cmdSet)
+	expanded := *cmdSet
+	expanded.Commands = withShells(withEnvDefaults(withSharedArgs(cmdSet)), shell)
+	cmdSet = &expanded
+
+	skipSteps, onlySteps := opts.SkipSteps, opts.OnlySteps
+	fromStep, toStep := opts.FromStep, opts.ToStep
+	if opts.Resume != nil {
+		// A resumed run keeps the step selection of the original run
+		skipSteps, onlySteps = opts.Resume.SkipSteps, opts.Resume.OnlySteps
+		fromStep, toStep = opts.Resume.FromStep, opts.Resume.ToStep
+	}
+
 	// Get platform
 	platform, err := config.GetPlatform()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: Failed to get platform: %v, using auto-detected\n", err)
 		platform = config.DetectPlatform()
 	}
+	if opts.Resume != nil && opts.Resume.Platform != "" {
+		platform = opts.Resume.Platform
+	}
 	
+	// Steps can also be selected by id or tag
+	skipNums, err := resolveStepIDs(cmdSet.Commands, skipSteps)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --skip format: %v\n", err)
+		os.Exit(1)
+	}
+	onlyNums, err := resolveStepIDs(cmdSet.Commands, onlySteps)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid --only format: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Filter commands if flags are provided
 	commandsToRun := cmdSet.Commands
 	var originalIndices []int
 	
 	if skipSteps != "" || onlySteps != "" {
 		var err error
-		commandsToRun, originalIndices, err = filterCommands(cmdSet.Commands, skipSteps, onlySteps)
+		commandsToRun, originalIndices, err = filterCommands(cmdSet.Commands, skipNums, onlyNums)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
@@ -311,6 +603,20 @@ func executeCommandSet(cmdSet *repo.CommandSet, skipSteps, onlySteps string, yes
 		}
 	}
 	
+	// Narrow the selection down to the steps from --from to --to
+	if fromStep != "" || toStep != "" {
+		first, last, err := resolveStepRange(cmdSet.Commands, fromStep, toStep)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		commandsToRun, originalIndices = filterStepRange(commandsToRun, originalIndices, first, last)
+		if len(commandsToRun) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: No commands to execute after filtering\n")
+			os.Exit(1)
+		}
+	}
+
 	// Safety check: ensure we have commands to run
 	if len(commandsToRun) == 0 {
 		fmt.Fprintf(os.Stderr, "Error: No commands found in command set '%s'\n", cmdSet.Name)
@@ -326,18 +632,344 @@ func executeCommandSet(cmdSet *repo.CommandSet, skipSteps, onlySteps string, yes
 		}
 	}
 	
-	fmt.Printf("\n📦 Command Set: %s\n", cmdSet.Name)
-	fmt.Printf("📝 Description: %s\n", cmdSet.Description)
-	fmt.Printf("🔢 Version: %s\n", cmdSet.Version)
-	fmt.Printf("🖥️  Platform: %s\n", platform)
+	argNames := make(map[string]bool)
+	for i, cmd := range commandsToRun {
+		for _, argDef := range cmd.Args {
+			if render.IsFilter(argDef.Name) {
+				fmt.Fprintf(os.Stderr, "Error: step %d (%s): argument '%s' has the name of a template filter\n", originalIndices[i], cmd.Description, argDef.Name)
+				os.Exit(1)
+			}
+			argNames[argDef.Name] = true
+		}
+	}
+
+	persistent, err := isPersistentSession(cmdSet.Session)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if persistent {
+		if err := validateSessionShell(shell); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	quote := quotesArgs(cmdSet)
+
+	deps, err := resolveDependencies(cmdSet.Commands)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if opts.Jobs < 0 {
+		fmt.Fprintf(os.Stderr, "Error: --jobs must be at least 1, got: %d\n", opts.Jobs)
+		os.Exit(1)
+	}
+	jobs := opts.Jobs
+	if jobs == 0 {
+		jobs = defaultJobs
+	}
+	// Steps only run concurrently when they declare dependencies; a persistent session has one shell
+	graph := hasDependencies(cmdSet.Commands)
+	parallel := graph && jobs > 1 && !persistent && !opts.Step
+
+	reporter, err := newRunReporter(opts.Output, parallel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	// JSON output is for tools, which can't answer the confirmation prompt
+	jsonOutput := opts.Output == outputJSON
+	if jsonOutput && !opts.Yes {
+		fmt.Fprintf(os.Stderr, "Error: --output json requires --yes\n")
+		os.Exit(1)
+	}
+	// Step mode asks about every step instead of confirming the run once
+	if opts.Step {
+		if opts.Yes || jsonOutput {
+			fmt.Fprintf(os.Stderr, "Error: --step cannot be used with --yes or --output json\n")
+			os.Exit(1)
+		}
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			fmt.Fprintf(os.Stderr, "Error: --step requires a terminal\n")
+			os.Exit(1)
+		}
+	}
+	if opts.Edit && !term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprintf(os.Stderr, "Error: --edit requires a terminal\n")
+		os.Exit(1)
+	}
+
+	// Validate step settings before anything runs
+	whenNames := newWhenNames(original)
+	policies := make([]retryPolicy, len(commandsToRun))
+	for i, cmd := range commandsToRun {
+		policy, err := parseRetryPolicy(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: step %d (%s): %v\n", originalIndices[i], cmd.Description, err)
+			os.Exit(1)
+		}
+		if persistent && policy.timeout > 0 {
+			// Killing a timed out step would take the whole session down with it
+			fmt.Fprintf(os.Stderr, "Error: step %d (%s): timeout cannot be used with session: persistent\n", originalIndices[i], cmd.Description)
+			os.Exit(1)
+		}
+		policies[i] = policy
+
+		if err := validateShell(commandShell(cmd)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: step %d (%s): %v\n", originalIndices[i], cmd.Description, err)
+			os.Exit(1)
+		}
+		if persistent && !commandShell(cmd).equal(shell) {
+			// All steps of a persistent session run in the session's shell
+			fmt.Fprintf(os.Stderr, "Error: step %d (%s): shell and shell_options cannot be set per step with session: persistent\n", originalIndices[i], cmd.Description)
+			os.Exit(1)
+		}
+
+		if err := validateScript(cmd); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: step %d (%s): %v\n", originalIndices[i], cmd.Description, err)
+			os.Exit(1)
+		}
+
+		if _, err := evaluateWhen(cmd.When, buildWhenFacts(platform, nil, nil, nil)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: step %d (%s): %v\n", originalIndices[i], cmd.Description, err)
+			os.Exit(1)
+		}
+		if err := checkWhenNames(cmd, whenNames); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: step %d (%s): %v\n", originalIndices[i], cmd.Description, err)
+			os.Exit(1)
+		}
+
+		if cmd.Register != "" {
+			if !varNamePattern.MatchString(cmd.Register) {
+				fmt.Fprintf(os.Stderr, "Error: step %d (%s): invalid register name '%s'\n", originalIndices[i], cmd.Description, cmd.Register)
+				os.Exit(1)
+			}
+			if render.IsFilter(cmd.Register) {
+				fmt.Fprintf(os.Stderr, "Error: step %d (%s): register '%s' has the name of a template filter\n", originalIndices[i], cmd.Description, cmd.Register)
+				os.Exit(1)
+			}
+			if argNames[cmd.Register] {
+				fmt.Fprintf(os.Stderr, "Error: step %d (%s): register '%s' conflicts with an argument of the same name\n", originalIndices[i], cmd.Description, cmd.Register)
+				os.Exit(1)
+			}
+		}
+	}
+
+	// When resuming, drop the steps that already completed in the failed run
+	if opts.Resume != nil {
+		var remaining []repo.Command
+		var remainingIndices []int
+		var remainingPolicies []retryPolicy
+		for i, cmd := range commandsToRun {
+			if !opts.Resume.isCompleted(originalIndices[i]) {
+				remaining = append(remaining, cmd)
+				remainingIndices = append(remainingIndices, originalIndices[i])
+				remainingPolicies = append(remainingPolicies, policies[i])
+			}
+		}
+		if len(remaining) == 0 {
+			if jsonOutput {
+				reporter.runFinished(opts.Resume, nil, 0)
+			} else {
+				fmt.Printf("✅ Run %s has no steps left to execute\n", opts.Resume.ID)
+			}
+			if err := opts.Resume.remove(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to remove run state: %v\n", err)
+			}
+			return
+		}
+		commandsToRun, originalIndices, policies = remaining, remainingIndices, remainingPolicies
+	}
+
+	// Plan the steps. Dependencies on steps that are not part of this run, because
+	// they were filtered out or completed before resuming, count as finished.
+	positions := make(map[int]int)
+	for i, num := range originalIndices {
+		positions[num] = i
+	}
+	steps := make([]*plannedStep, len(commandsToRun))
+	stepDeps := make([][]int, len(commandsToRun))
+	for i, cmd := range commandsToRun {
+		for _, dep := range deps[originalIndices[i]-1] {
+			if pos, exists := positions[dep+1]; exists {
+				stepDeps[i] = append(stepDeps[i], pos)
+			}
+		}
+		steps[i] = &plannedStep{
+			cmd:    cmd,
+			num:    originalIndices[i],
+			label:  stepLabel(cmd, originalIndices[i]),
+			policy: policies[i],
+			deps:   stepDeps[i],
+		}
+	}
+	if graph && !parallel {
+		// Run one step at a time, each after its dependencies
+		ordered := make([]*plannedStep, 0, len(steps))
+		for _, i := range dependencyOrder(stepDeps) {
+			ordered = append(ordered, steps[i])
+		}
+		steps = ordered
+	}
 	
-	if skipSteps != "" {
-		fmt.Printf("⏭️  Skipping steps: %s\n", skipSteps)
-	} else if onlySteps != "" {
-		fmt.Printf("🎯 Running only steps: %s\n", onlySteps)
+	// Resolve every argument before anything runs, so a missing argument can't
+	// stop the run halfway and prompts don't interrupt command output
+	var argCommands []repo.Command
+	for _, cmd := range commandsToRun {
+		if getCommandForPlatform(cmd, platform) != "" {
+			argCommands = append(argCommands, cmd)
+		}
+	}
+	argCommands = withPrompts(argCommands, opts.Reenter)
+
+	// Values from the environment are overridden by those of the failed run
+	// being resumed, then the earlier run, args files and --args
+	providedArgs := envArgValues(argCommands)
+	if opts.Resume != nil {
+		// Reuse the arguments resolved by the failed run so only secrets are
+		// prompted for again
+		for k, v := range opts.Resume.Args {
+			providedArgs[k] = v
+		}
+	}
+	for k, v := range opts.Values {
+		providedArgs[k] = v
+	}
+	fileArgs, err := loadArgsFiles(opts.ArgsFiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for k, v := range fileArgs {
+		providedArgs[k] = v
+	}
+	for k, v := range parseArgsFlag(opts.Args) {
+		providedArgs[k] = v
+	}
+
+	facts := builtinFacts(platform, "")
+	argValues, err := collectCommandArgs(argCommands, providedArgs, facts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, step := range steps {
+		step.args = stepArgs(step.cmd, argValues)
+	}
+	if err := exportSecrets(argCommands, argValues); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to pass secret arguments: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Render every step before anything runs, so an unresolved placeholder
+	// can't stop the run halfway
+	previews := make([]renderedStep, len(commandsToRun))
+	for i, cmd := range commandsToRun {
+		if getCommandForPlatform(cmd, platform) == "" {
+			continue
+		}
+		values := withPendingPlaceholders(cmdSet.Commands, redactValues(templateValues(cmd, facts, stepArgs(cmd, argValues))))
+		rendered, err := renderStep(cmd, platform, values, quote)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: step %d (%s): %v\n", originalIndices[i], cmd.Description, err)
+			os.Exit(1)
+		}
+		previews[i] = rendered
+	}
+
+	// With --edit, the rendered commands are edited before anything runs
+	var edits map[int]string
+	var removed map[int]bool
+	if opts.Edit {
+		var plan []planStep
+		for i, cmd := range commandsToRun {
+			if getCommandForPlatform(cmd, platform) == "" {
+				continue
+			}
+			// Secrets are written as references to the variables holding them
+			values := withPendingPlaceholders(cmdSet.Commands, templateValues(cmd, facts, stepArgs(cmd, argValues)))
+			rendered, _ := renderStep(cmd, platform, values, quote) // Rendered without error above
+			plan = append(plan, planStep{num: originalIndices[i], description: cmd.Description, command: rendered.command})
+		}
+
+		var ok bool
+		edits, removed, ok, err = editRunPlan(cmdSet, platform, plan)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to edit the plan: %v\n", err)
+			os.Exit(1)
+		}
+		if !ok {
+			fmt.Println("Cancelled.")
+			return
+		}
+
+		planned := make(map[int]*plannedStep, len(steps))
+		for _, step := range steps {
+			planned[step.num] = step
+		}
+		for i := range commandsToRun {
+			num := originalIndices[i]
+			planned[num].removed = removed[num]
+			command, exists := edits[num]
+			if !exists {
+				continue
+			}
+			setEditedCommand(&commandsToRun[i], command)
+			planned[num].cmd = commandsToRun[i]
+
+			values := withPendingPlaceholders(cmdSet.Commands, redactValues(templateValues(commandsToRun[i], facts, stepArgs(commandsToRun[i], argValues))))
+			if previews[i], err = renderStep(commandsToRun[i], platform, values, quote); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: step %d (%s): %v\n", num, commandsToRun[i].Description, err)
+				os.Exit(1)
+			}
+		}
+
+		if len(edits) > 0 || len(removed) > 0 {
+			if confirm("Save the edited plan as a new local version?") {
+				saveEditedPlan(original, edits, removed)
+			}
+		} else {
+			fmt.Println("ℹ️  The plan is unchanged")
+		}
 	}
 	
-	fmt.Printf("📋 Commands to execute:\n\n")
+	// The preview is for people, JSON output starts with the run_start event
+	if !jsonOutput {
+		fmt.Printf("\n📦 Command Set: %s\n", cmdSet.Name)
+		fmt.Printf("📝 Description: %s\n", cmdSet.Description)
+		fmt.Printf("🔢 Version: %s\n", cmdSet.Version)
+		fmt.Printf("🖥️  Platform: %s\n", platform)
+		if persistent {
+			fmt.Printf("🐚 Session: persistent (steps share one shell)\n")
+		}
+		if !shell.isDefault() {
+			fmt.Printf("🐚 Shell: %s\n", shell)
+		}
+		if parallel {
+			fmt.Printf("🔀 Parallel: up to %d steps at a time\n", jobs)
+		}
+		if opts.Step {
+			fmt.Printf("👣 Step mode: you are asked before each step\n")
+		}
+	
+		if opts.Resume != nil {
+			fmt.Printf("🔁 Resuming run %s from step %d\n", opts.Resume.ID, originalIndices[0])
+			if persistent {
+				fmt.Printf("⚠️  A new session shell is started, directory and environment changes from completed steps are not restored\n")
+			}
+		}
+		if skipSteps != "" {
+			fmt.Printf("⏭️  Skipping steps: %s\n", skipSteps)
+		} else if onlySteps != "" {
+			fmt.Printf("🎯 Running only steps: %s\n", onlySteps)
+		}
+		if fromStep != "" || toStep != "" {
+			fmt.Printf("📍 Running steps %s\n", describeStepRange(fromStep, toStep))
+		}
+	
+		fmt.Printf("📋 Commands to execute:\n\n")
+	}
 
 	// Safety check: ensure originalIndices matches commandsToRun length
 	if len(originalIndices) != len(commandsToRun) {
@@ -349,70 +981,84 @@ func executeCommandSet(cmdSet *repo.CommandSet, skipSteps, onlySteps string, yes
 	}
 
 	hasUnsupportedCommands := false
-	providedArgs := parseArgsFlag(argsFlag)
 	
-	for i, cmd := range commandsToRun {
-		originalNum := i + 1 // Default to 1-indexed position
-		if i < len(originalIndices) {
-			originalNum = originalIndices[i]
-		}
-		fmt.Printf("  %d. %s\n", originalNum, cmd.Description)
-		command := getCommandForPlatform(cmd, platform)
-		if command == "" {
-			fmt.Printf("     ⚠️  No command available for platform '%s'\n", platform)
-			if len(cmd.Platforms) > 0 {
-				availablePlatforms := make([]string, 0, len(cmd.Platforms))
-				for p := range cmd.Platforms {
-					availablePlatforms = append(availablePlatforms, p)
-				}
-				fmt.Printf("     Available platforms: %s\n", strings.Join(availablePlatforms, ", "))
+	if !jsonOutput {
+		for i, cmd := range commandsToRun {
+			originalNum := i + 1 // Default to 1-indexed position
+			if i < len(originalIndices) {
+				originalNum = originalIndices[i]
 			}
-			hasUnsupportedCommands = true
-		} else {
-			// Show command with placeholders or substituted values
-			previewCommand := command
-			if len(cmd.Args) > 0 {
-				// Try to substitute with provided args, or show placeholders
-				previewArgs := make(map[string]string)
-				for _, argDef := range cmd.Args {
-					if val, exists := providedArgs[argDef.Name]; exists {
-						previewArgs[argDef.Name] = val
-					} else if argDef.Default != "" {
-						previewArgs[argDef.Name] = argDef.Default
-					} else {
-						previewArgs[argDef.Name] = fmt.Sprintf("{{%s}}", argDef.Name)
+			fmt.Printf("  %s\n", stepHeading(cmd, originalNum))
+			if len(cmd.DependsOn) > 0 {
+				fmt.Printf("     🔗 Depends on: %s\n", strings.Join(cmd.DependsOn, ", "))
+			}
+			command := getCommandForPlatform(cmd, platform)
+			if removed[originalNum] {
+				fmt.Printf("     ⏭️  Removed from the plan (will be skipped)\n")
+			} else if command == "" {
+				fmt.Printf("     ⚠️  No command available for platform '%s'\n", platform)
+				if len(cmd.Platforms) > 0 {
+					availablePlatforms := make([]string, 0, len(cmd.Platforms))
+					for p := range cmd.Platforms {
+						availablePlatforms = append(availablePlatforms, p)
 					}
+					fmt.Printf("     Available platforms: %s\n", strings.Join(availablePlatforms, ", "))
 				}
-				previewCommand = substituteArgs(command, previewArgs)
-			}
-			fmt.Printf("     $ %s\n", previewCommand)
-			
-			// Show which arguments will be needed
-			if len(cmd.Args) > 0 {
-				argsToPrompt := []string{}
-				for _, argDef := range cmd.Args {
-					// Show all args that have prompts defined (even if they have defaults)
-					if _, exists := providedArgs[argDef.Name]; !exists && argDef.Prompt != "" {
-						argsToPrompt = append(argsToPrompt, argDef.Name)
+				hasUnsupportedCommands = true
+			} else {
+				// Show the command as it will run, with placeholders for step output
+				previewArgs := stepArgs(cmd, argValues)
+				fmt.Printf("     $ %s\n", indentCommand(previews[i].command, "       "))
+				if _, edited := edits[originalNum]; edited {
+					fmt.Printf("     ✏️  Edited\n")
+				}
+				if stepShell := commandShell(cmd); !stepShell.equal(shell) {
+					fmt.Printf("     🐚 Shell: %s\n", stepShell)
+				}
+				if desc := policies[i].String(); desc != "" {
+					fmt.Printf("     ⏱️  %s\n", desc)
+				}
+
+				// Conditions on earlier step results can only be evaluated during execution
+				if cmd.When != "" {
+					fmt.Printf("     ❓ When: %s\n", cmd.When)
+					if !strings.Contains(cmd.When, "steps.") {
+						if ok, err := evaluateWhen(cmd.When, buildWhenFacts(platform, previewArgs, nil, nil)); err == nil && !ok {
+							fmt.Printf("     ⏭️  Condition not met (will be skipped)\n")
+						}
 					}
 				}
-				if len(argsToPrompt) > 0 {
-					fmt.Printf("     📝 Will prompt for: %s\n", strings.Join(argsToPrompt, ", "))
+
+				// Checks may use sudo or the network, so they only run before
+				// the confirmation when asked to
+				if previewCheck := previews[i].check; previewCheck != "" {
+					fmt.Printf("     🔍 Check: %s\n", previewCheck)
+					if opts.Check && !strings.Contains(previewCheck, "{{") && (processRunner{shell: commandShell(cmd)}).check(previewCheck, policies[i].timeout) {
+						fmt.Printf("     ✔️  Already satisfied (will be skipped)\n")
+					}
+				}
+			
+				if cmd.Register != "" {
+					fmt.Printf("     📥 Output saved as: {{%s}}\n", cmd.Register)
+				}
+
+				if undo := previews[i].undo; undo != "" {
+					fmt.Printf("     ↩️  Undo: %s\n", undo)
 				}
 			}
+			fmt.Println()
 		}
-		fmt.Println()
-	}
 
-	if hasUnsupportedCommands {
-		fmt.Printf("⚠️  Warning: Some commands are not available for platform '%s'\n", platform)
-		fmt.Printf("   Consider changing your platform with: shelldock config set <platform>\n")
-		fmt.Printf("   Or use --yes flag to skip unsupported commands during execution\n")
-		fmt.Println()
+		if hasUnsupportedCommands {
+			fmt.Printf("⚠️  Warning: Some commands are not available for platform '%s'\n", platform)
+			fmt.Printf("   Consider changing your platform with: shelldock config set <platform>\n")
+			fmt.Printf("   Or use --yes flag to skip unsupported commands during execution\n")
+			fmt.Println()
+		}
 	}
 
 	// Skip prompt if --yes flag is set
-	if !yesFlag {
+	if !opts.Yes && !opts.Step {
 		// Check if stdin is a terminal
 		if !term.IsTerminal(int(os.Stdin.Fd())) {
 			// Not a terminal (e.g., piped input), don't prompt
@@ -444,49 +1090,132 @@ func executeCommandSet(cmdSet *repo.CommandSet, skipSteps, onlySteps string, yes
 		}
 	}
 
-	fmt.Println("\n🚀 Executing commands...")
-	fmt.Println()
+	checkpoint := opts.Resume
+	if checkpoint == nil {
+		checkpoint, err = newRunCheckpoint(cmdSet.Name, cmdSet.Version, platform)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		checkpoint.Local = opts.Local
+		checkpoint.SkipSteps = skipSteps
+		checkpoint.OnlySteps = onlySteps
+		checkpoint.FromStep = fromStep
+		checkpoint.ToStep = toStep
+	}
+	checkpoint.setArgs(argCommands, argValues)
+	if err := checkpoint.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to save run state, this run cannot be resumed: %v\n", err)
+	}
 
-	for i, cmd := range commandsToRun {
-		originalNum := i + 1 // Default to 1-indexed position
-		if i < len(originalIndices) {
-			originalNum = originalIndices[i]
-		}
-		command := getCommandForPlatform(cmd, platform)
-		if command == "" {
-			fmt.Printf("[%d/%d] %s (step %d)\n", i+1, len(commandsToRun), cmd.Description, originalNum)
-			fmt.Printf("⚠️  Skipping: No command available for platform '%s'\n\n", platform)
-			continue
+	// Persistent sessions run every step in one shell, kept alongside the run state
+	var runner commandRunner = processRunner{}
+	var session *shellSession
+	if persistent {
+		runDir, err := checkpoint.dir()
+		if err == nil {
+			session, err = newShellSession(filepath.Join(runDir, "session"), shell)
 		}
-		
-		// Collect arguments for this command
-		cmdArgs := collectCommandArgs(cmd, providedArgs)
-		
-		// Substitute arguments in command
-		command = substituteArgs(command, cmdArgs)
-		
-		fmt.Printf("[%d/%d] %s (step %d)\n", i+1, len(commandsToRun), cmd.Description, originalNum)
-		fmt.Printf("$ %s\n", command)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		runner = session
+	}
 
-		execCmd := exec.Command("sh", "-c", command)
-		execCmd.Stdout = os.Stdout
-		execCmd.Stderr = os.Stderr
-		execCmd.Stdin = os.Stdin
+	reporter.runStarted(checkpoint, steps)
 
-		if err := execCmd.Run(); err != nil {
-			if cmd.SkipOnError {
-				fmt.Printf("⚠️  Command failed but continuing (skip_on_error=true)\n\n")
-				continue
+	runDir, _ := checkpoint.dir()
+	state := &runState{
+		checkpoint:   checkpoint,
+		platform:     platform,
+		facts:        builtinFacts(platform, runDir),
+		runner:       runner,
+		providedArgs: mergeValues(providedArgs, argValues),
+		quote:        quote,
+		total:        len(steps),
+		reporter:     reporter,
+	}
+	if opts.Step {
+		state.stepper = newStepper(platform, os.Stdin)
+	}
+
+	var failures []stepFailure
+	if parallel {
+		failures = state.runParallel(steps, jobs)
+	} else {
+		failures = state.runSequential(steps)
+	}
+
+	if len(failures) > 0 {
+		var saveErr error
+		resumeFrom := 0
+		for _, f := range failures {
+			if err := checkpoint.markFailed(f.num, f.result); err != nil {
+				saveErr = err
+			}
+			if resumeFrom == 0 || f.num < resumeFrom {
+				resumeFrom = f.num
 			}
-			fmt.Fprintf(os.Stderr, "\n❌ Command failed: %v\n", err)
-			os.Exit(1)
 		}
 
-		fmt.Println("✅ Success")
-		fmt.Println()
+		// Offer to get back to the state before the run
+		results := make(map[int]stepResult, len(checkpoint.Results))
+		for num, result := range checkpoint.Results {
+			results[num] = result
+		}
+		if undone := offerRollback(cmdSet.Commands, checkpoint, platform, quote, runner, reporter, opts); undone > 0 && undone < resumeFrom {
+			resumeFrom = undone
+		}
+		var rolledBack []int
+		for num, result := range results {
+			if result.Status == stepSuccess && !checkpoint.isCompleted(num) {
+				rolledBack = append(rolledBack, num)
+			}
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(rolledBack)))
+		recordHistory(newRunRecord(checkpoint, cmdSet.Commands, results, stepFailed, rolledBack))
+
+		if saveErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to save run state: %v\n", saveErr)
+		} else {
+			fmt.Fprintf(os.Stderr, "💾 Run state saved. Resume from step %d with: shelldock resume %s\n", resumeFrom, checkpoint.ID)
+		}
+		reporter.runFinished(checkpoint, failures, resumeFrom)
+		closeSession(session)
+		os.Exit(1)
+	}
+
+	closeSession(session)
+	recordHistory(newRunRecord(checkpoint, cmdSet.Commands, checkpoint.Results, stepSuccess, nil))
+	if err := checkpoint.remove(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to remove run state: %v\n", err)
 	}
+	reporter.runFinished(checkpoint, nil, 0)
+}
 
-	fmt.Println("🎉 All commands executed successfully!")
+// closeSession ends the session shell of a persistent run, if any
+func closeSession(session *shellSession) {
+	if session == nil {
+		return
+	}
+	if err := session.close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Session shell did not exit cleanly: %v\n", err)
+	}
+}
+
+// recordHistory writes the history entry of a finished run, warning if it fails
+func recordHistory(record *runRecord) {
+	if err := record.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to record run history: %v\n", err)
+	}
+}
+
+// saveCheckpoint reports a failure to persist the run state without aborting the run
+func saveCheckpoint(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to save run state: %v\n", err)
+	}
 }
 
 var runCmd = &cobra.Command{
@@ -501,7 +1230,41 @@ You can skip specific steps with --skip:
 
 Or run only specific steps with --only:
   shelldock run docker --only 1,3,5
-  shelldock run docker --only 1-3`,
+  shelldock run docker --only 1-3
+
+Steps with an id or tags can also be selected by id or tag, and
+ranges may be open-ended:
+  shelldock run go --only install,verify
+  shelldock run go --only tag:verify
+  shelldock run go --skip 3-
+
+Or run a contiguous part of the set with --from and --to:
+  shelldock run go --from install --to verify
+
+Steps that declare depends_on run as soon as their dependencies
+finished, up to --jobs steps at a time:
+  shelldock run go --jobs 2
+
+If a step fails, the run state is saved and can be continued
+from the failed step without prompting for arguments again:
+  shelldock run docker --resume
+  shelldock resume <run-id>
+
+Steps that define an undo command can be rolled back when a later
+step fails. You are asked in a terminal, or use --rollback-on-failure:
+  shelldock run ufw --yes --rollback-on-failure
+
+To walk through a set one step at a time, deciding before each step
+whether to run, skip or edit it, and after a failure whether to retry,
+skip or debug it:
+  shelldock run swap --step
+
+To tweak the rendered commands in $EDITOR before they run, and
+optionally keep the edits as a new local version:
+  shelldock run docker --edit
+
+For CI, --output json prints the run as newline-delimited JSON events:
+  shelldock run go --yes --output json`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
@@ -515,26 +1278,62 @@ Or run only specific steps with --only:
 			version = versionFlag
 		}
 		
+		opts := runOptions{
+			SkipSteps: skipSteps,
+			OnlySteps: onlySteps,
+			FromStep:  fromStep,
+			ToStep:    toStep,
+			Yes:       yesFlag,
+			Args:      argsFlag,
+			ArgsFiles: argsFileFlag,
+			Local:     localFlag,
+			Jobs:      jobsFlag,
+			Output:    outputFlag,
+			Step:      stepFlag,
+			Edit:      editFlag,
+			Check:     checkFlag,
+
+			RollbackOnFailure: rollbackOnFailureFlag,
+		}
+
+		// Continue the most recent failed run of this command set
+		if resumeFlag {
+			checkpoint, err := findLatestCheckpoint(name)
+			handleError(err)
+			version = checkpoint.Version
+			opts.Local = checkpoint.Local
+			opts.Resume = checkpoint
+		}
+		
 		manager, err := repo.NewManager()
 		handleError(err)
 
-		cmdSet, err := manager.GetCommandSet(name, localFlag, version)
+		cmdSet, err := manager.GetCommandSet(name, opts.Local, version)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 
-		executeCommandSet(cmdSet, skipSteps, onlySteps, yesFlag, argsFlag)
+		executeCommandSet(cmdSet, opts)
 	},
 }
 
 func init() {
 	runCmd.Flags().BoolVarP(&localFlag, "local", "l", false, "Only check local repository (skip bundled repository)")
-	runCmd.Flags().StringVar(&skipSteps, "skip", "", "Skip specific steps (comma-separated or range, e.g., 1,2,3 or 1-3)")
-	runCmd.Flags().StringVar(&onlySteps, "only", "", "Run only specific steps (comma-separated or range, e.g., 1,3,5 or 1-3)")
+	runCmd.Flags().StringVar(&skipSteps, "skip", "", "Skip specific steps by number, range, id or tag (e.g., 1,2,3, 1-3, 5-, install or tag:verify)")
+	runCmd.Flags().StringVar(&onlySteps, "only", "", "Run only specific steps by number, range, id or tag (e.g., 1,3,5, 1-3, 5-, install or tag:verify)")
+	runCmd.Flags().StringVar(&fromStep, "from", "", "Start at a step, given by number or id")
+	runCmd.Flags().StringVar(&toStep, "to", "", "Stop after a step, given by number or id")
 	runCmd.Flags().StringVar(&versionFlag, "ver", "", "Run specific version or tag (default: latest)")
 	runCmd.Flags().StringVar(&versionFlag, "version", "", "Run specific version or tag (default: latest) - alias for --ver")
 	runCmd.Flags().BoolVarP(&yesFlag, "yes", "y", false, "Execute commands without prompting for confirmation")
 	runCmd.Flags().StringVar(&argsFlag, "args", "", "Provide arguments as key=value pairs (e.g., --args name=John,email=john@example.com)")
+	runCmd.Flags().StringArrayVar(&argsFileFlag, "args-file", nil, "Read arguments from a YAML, JSON or .env file (can be repeated, later files win)")
+	runCmd.Flags().BoolVar(&resumeFlag, "resume", false, "Resume the last failed run of this command set from the failed step")
+	runCmd.Flags().IntVarP(&jobsFlag, "jobs", "j", defaultJobs, "Maximum number of steps to run at the same time when steps declare depends_on")
+	runCmd.Flags().StringVarP(&outputFlag, "output", "o", outputText, "Output format: text, or json for newline-delimited JSON events (requires --yes)")
+	runCmd.Flags().BoolVar(&rollbackOnFailureFlag, "rollback-on-failure", false, "Run the undo commands of completed steps in reverse order if a step fails")
+	runCmd.Flags().BoolVar(&stepFlag, "step", false, "Ask before each step whether to run, skip or edit it, and after a failure whether to retry, skip or debug it")
+	runCmd.Flags().BoolVar(&editFlag, "edit", false, "Edit the rendered commands in $EDITOR before running them")
+	runCmd.Flags().BoolVar(&checkFlag, "check", false, "Run check commands in the preview to show which steps are already satisfied")
 }
-
@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/shelldock/shelldock/internal/repo"
@@ -132,6 +134,25 @@ func TestGetCommandForPlatform(t *testing.T) {
 	}
 }
 
+func TestGetCheckForPlatform(t *testing.T) {
+	cmd := repo.Command{
+		Check: "test -f /swapfile",
+		CheckPlatforms: map[string]string{
+			"alpine": "ls /swapfile",
+		},
+	}
+
+	if check := getCheckForPlatform(cmd, "alpine"); check != "ls /swapfile" {
+		t.Errorf("Expected platform check, got %q", check)
+	}
+	if check := getCheckForPlatform(cmd, "ubuntu"); check != "test -f /swapfile" {
+		t.Errorf("Expected fallback check, got %q", check)
+	}
+	if check := getCheckForPlatform(repo.Command{}, "ubuntu"); check != "" {
+		t.Errorf("Expected no check, got %q", check)
+	}
+}
+
 func TestParseArgsFlag(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -160,7 +181,7 @@ func TestParseArgsFlag(t *testing.T) {
 	}
 }
 
-func TestSubstituteArgs(t *testing.T) {
+func TestRenderStep(t *testing.T) {
 	tests := []struct {
 		command  string
 		args     map[string]string
@@ -170,36 +191,92 @@ func TestSubstituteArgs(t *testing.T) {
 		{"git config --global user.name \"{{name}}\"", map[string]string{"name": "John Doe"}, "git config --global user.name \"John Doe\""},
 		{"echo {{name}} and {{email}}", map[string]string{"name": "John", "email": "john@example.com"}, "echo John and john@example.com"},
 		{"echo hello", map[string]string{}, "echo hello"},
-		{"echo {{missing}}", map[string]string{}, "echo {{missing}}"},
 	}
 
 	for _, tt := range tests {
-		result := substituteArgs(tt.command, tt.args)
-		if result != tt.expected {
-			t.Errorf("substituteArgs(%q, %v) = %q, expected %q", tt.command, tt.args, result, tt.expected)
+		rendered, err := renderStep(repo.Command{Command: tt.command}, "ubuntu", templateValues(repo.Command{}, nil, tt.args), false)
+		if err != nil || rendered.command != tt.expected {
+			t.Errorf("renderStep(%q, %v) = %q, %v, expected %q", tt.command, tt.args, rendered.command, err, tt.expected)
 		}
 	}
-}
 
-func TestCollectCommandArgs(t *testing.T) {
-	// This test would require mocking stdin, which is complex
-	// For now, we test the logic with provided args only
+	// Placeholders without a value are an error instead of being passed to the shell
+	if _, err := renderStep(repo.Command{Command: "echo {{missing}}"}, "ubuntu", templateValues(repo.Command{}, nil), false); err == nil {
+		t.Error("Expected an error for an unresolved placeholder")
+	}
+
 	cmd := repo.Command{
+		Command: "apt-get install -y {{range packages}}{{.}} {{end}}{{if eq mode \"dev\"}}--dry-run{{end}}",
+		Check:   "dpkg -s {{packages | raw}}",
+		Undo:    "echo {{note}}",
 		Args: []repo.ArgumentDef{
-			{Name: "name", Required: true},
-			{Name: "email", Required: true},
-			{Name: "age", Default: "25", Required: false},
+			{Name: "packages", Type: "list", Default: "git,curl"},
+			{Name: "mode", Default: "dev"},
+			{Name: "note"},
+		},
+	}
+	rendered, err := renderStep(cmd, "ubuntu", templateValues(cmd, nil, map[string]string{"mode": "prod"}), true)
+	if err != nil {
+		t.Fatalf("renderStep failed: %v", err)
+	}
+	if rendered.command != "apt-get install -y 'git' 'curl' " {
+		t.Errorf("Unexpected command: %q", rendered.command)
+	}
+	if rendered.check != "dpkg -s git curl" {
+		t.Errorf("Unexpected check: %q", rendered.check)
+	}
+	if rendered.undo != "echo ''" {
+		t.Errorf("Expected an optional argument without a value to render empty, got %q", rendered.undo)
+	}
+
+	// Before a run, unknown values and step output are shown as placeholders
+	commands := []repo.Command{{Command: "uname -r", Register: "kernel"}, cmd}
+	cmd.Command = "echo {{note}} {{kernel}} {{shelldock_run_dir}}"
+	rendered, err = renderStep(cmd, "ubuntu", showValues(commands, cmd, builtinFacts("ubuntu", "")), true)
+	if err != nil || rendered.command != "echo {{note}} {{kernel}} {{shelldock_run_dir}}" {
+		t.Errorf("Expected placeholders, got %q, %v", rendered.command, err)
+	}
+
+	// Built-in facts are available, and arguments of the same name take precedence
+	cmd = repo.Command{
+		Command: "echo {{platform}}/{{arch}} {{shelldock_run_dir}} {{user}}",
+		Args:    []repo.ArgumentDef{{Name: "user", Default: "deploy"}},
+	}
+	values := templateValues(cmd, builtinFacts("ubuntu", "/tmp/run"))
+	rendered, err = renderStep(cmd, "ubuntu", values, false)
+	if expected := "echo ubuntu/" + runtime.GOARCH + " /tmp/run deploy"; err != nil || rendered.command != expected {
+		t.Errorf("Expected %q, got %q, %v", expected, rendered.command, err)
+	}
+}
+
+func TestCollectCommandArgs(t *testing.T) {
+	// Tests run without a terminal, so nothing is prompted for
+	commands := []repo.Command{
+		{
+			Args: []repo.ArgumentDef{
+				{Name: "name", Required: true},
+				{Name: "email", Required: true},
+				{Name: "age", Default: "25", Required: false},
+			},
+		},
+		{
+			Args: []repo.ArgumentDef{
+				{Name: "name"},
+				{Name: "team", Required: true},
+			},
 		},
 	}
 
 	providedArgs := map[string]string{
 		"name":  "John",
 		"email": "john@example.com",
+		"team":  "ops",
 	}
 
-	// Note: This will fail in non-terminal, but we're testing the logic
-	// In a real scenario, we'd mock the terminal check
-	result := collectCommandArgs(cmd, providedArgs)
+	result, err := collectCommandArgs(commands, providedArgs, nil)
+	if err != nil {
+		t.Fatalf("collectCommandArgs failed: %v", err)
+	}
 
 	if result["name"] != "John" {
 		t.Errorf("Expected name 'John', got %q", result["name"])
@@ -207,6 +284,35 @@ func TestCollectCommandArgs(t *testing.T) {
 	if result["email"] != "john@example.com" {
 		t.Errorf("Expected email 'john@example.com', got %q", result["email"])
 	}
+	if age := stepArgs(commands[0], result)["age"]; age != "25" {
+		t.Errorf("Expected default age '25', got %q", age)
+	}
+
+	// Every missing required argument is reported at once
+	_, err = collectCommandArgs(commands, map[string]string{"name": "John"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "email, team") {
+		t.Errorf("Expected error listing email and team, got %v", err)
+	}
+
+	// Values are validated before anything runs
+	zero, hundred := 0, 100
+	typed := []repo.Command{
+		{Args: []repo.ArgumentDef{{Name: "swappiness", Type: "int", Min: &zero, Max: &hundred, Default: "60"}}},
+		{Args: []repo.ArgumentDef{{Name: "mode", Type: "enum", Choices: []string{"dev", "prod"}, Default: "dev"}}},
+	}
+	if _, err := collectCommandArgs(typed, map[string]string{"swappiness": "30", "mode": "prod"}, nil); err != nil {
+		t.Errorf("Expected valid arguments, got %v", err)
+	}
+	_, err = collectCommandArgs(typed, map[string]string{"swappiness": "banana", "mode": "test"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "swappiness") || !strings.Contains(err.Error(), "mode") {
+		t.Errorf("Expected errors for swappiness and mode, got %v", err)
+	}
+
+	// Invalid defaults are reported too
+	typed[0].Args[0].Default = "200"
+	if _, err := collectCommandArgs(typed, map[string]string{}, nil); err == nil {
+		t.Error("Expected an error for an invalid default")
+	}
 }
 
 func TestOriginalIndicesInitialization(t *testing.T) {
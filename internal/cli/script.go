@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/shelldock/shelldock/internal/render"
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+// plainWordPattern matches words the shell reads as they are, without quotes
+var plainWordPattern = regexp.MustCompile(`^[a-zA-Z0-9_@%+=:,./-]+$`)
+
+// shellInterpreters are the interpreters that read scripts as shell commands
+var shellInterpreters = map[string]bool{"sh": true, "bash": true, "dash": true, "ash": true, "zsh": true, "ksh": true, "mksh": true}
+
+// validateScript checks that a step with a script does not also have commands,
+// and that scripts for other interpreters than shells use no secret arguments,
+// which are only passed as shell references to environment variables
+func validateScript(cmd repo.Command) error {
+	if cmd.Script == "" {
+		if cmd.Interpreter != "" {
+			return errors.New("interpreter can only be used with script")
+		}
+		return nil
+	}
+	if cmd.Command != "" || len(cmd.Platforms) > 0 {
+		return errors.New("script cannot be used with command or platforms")
+	}
+
+	interpreter := scriptInterpreter(cmd.Interpreter, cmd.Script, commandShell(cmd))
+	if isShellInterpreter(interpreter) {
+		return nil
+	}
+	for _, argDef := range cmd.Args {
+		if argDef.IsSecret() && refersTo(cmd.Script, argDef.Name) {
+			return fmt.Errorf("secret argument '%s' can't be used in a %s script, read it from the environment variable %s instead", argDef.Name, path.Base(interpreter[0]), secretEnvName(argDef.Name))
+		}
+	}
+	return nil
+}
+
+// isShellInterpreter reports whether an interpreter is a shell. Scripts for
+// other interpreters get the values of arguments as they are, without quotes.
+func isShellInterpreter(interpreter []string) bool {
+	words := interpreter
+	if len(words) > 0 && path.Base(words[0]) == "env" {
+		words = words[1:]
+		for len(words) > 0 && strings.HasPrefix(words[0], "-") {
+			words = words[1:]
+		}
+	}
+	return len(words) > 0 && shellInterpreters[path.Base(words[0])]
+}
+
+// scriptInterpreter returns the program and arguments that run a script: the
+// step's interpreter (a name like bash or python3, or a shebang line), else the
+// script's own shebang line, else the step's shell
+func scriptInterpreter(interpreter, script string, shell shellSpec) []string {
+	if interpreter == "" {
+		firstLine, _, _ := strings.Cut(script, "\n")
+		if strings.HasPrefix(firstLine, "#!") {
+			interpreter = firstLine
+		}
+	}
+	if fields := strings.Fields(strings.TrimPrefix(interpreter, "#!")); len(fields) > 0 {
+		return fields
+	}
+	return shell.words()
+}
+
+// scriptCommand returns the shell command running a script file
+func scriptCommand(interpreter []string, path string) string {
+	return shellWords(append(interpreter[:len(interpreter):len(interpreter)], path))
+}
+
+// shellWords joins words into a shell command line, quoting the words that
+// the shell would not read as they are
+func shellWords(words []string) string {
+	parts := make([]string, 0, len(words))
+	for _, word := range words {
+		if plainWordPattern.MatchString(word) {
+			parts = append(parts, word)
+		} else {
+			parts = append(parts, render.ShellQuote(word))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// scriptHeredoc shows a script as the interpreter reading it from a here
+// document, which reads like the script and can be copied into a shell
+func scriptHeredoc(interpreter []string, script string) string {
+	script = strings.TrimRight(script, "\n")
+	lines := make(map[string]bool)
+	for _, line := range strings.Split(script, "\n") {
+		lines[line] = true
+	}
+	delimiter := "EOF"
+	for lines[delimiter] {
+		delimiter = "SHELLDOCK_" + delimiter
+	}
+	return fmt.Sprintf("%s <<'%s'\n%s\n%s", shellWords(interpreter), delimiter, script, delimiter)
+}
+
+// writeScript writes a rendered script to a temporary file only the current
+// user can read and returns its path
+func writeScript(script string) (string, error) {
+	file, err := os.CreateTemp("", "shelldock-script-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create script file: %w", err)
+	}
+	path := file.Name()
+
+	if !strings.HasSuffix(script, "\n") {
+		script += "\n"
+	}
+	_, err = file.WriteString(script)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(path, 0700)
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write script file: %w", err)
+	}
+	return path, nil
+}
+
+// indentCommand indents the lines after the first of a multi-line command, such
+// as a script, so they line up below it in an indented listing
+func indentCommand(command, indent string) string {
+	return strings.ReplaceAll(command, "\n", "\n"+indent)
+}
+
+// unwrapScript returns the script of a step's command as shown by
+// scriptHeredoc, or false if the command is not the step's script anymore
+func unwrapScript(cmd repo.Command, command string) (string, bool) {
+	if cmd.Script == "" {
+		return "", false
+	}
+	lines := strings.Split(command, "\n")
+	if len(lines) < 2 {
+		return "", false
+	}
+	first, last := lines[0], lines[len(lines)-1]
+	prefix := shellWords(scriptInterpreter(cmd.Interpreter, cmd.Script, commandShell(cmd))) + " <<'"
+	if !strings.HasPrefix(first, prefix) || first != prefix+last+"'" {
+		return "", false
+	}
+	return strings.Join(lines[1:len(lines)-1], "\n") + "\n", true
+}
@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+func TestValidateScript(t *testing.T) {
+	tests := []struct {
+		cmd     repo.Command
+		wantErr bool
+	}{
+		{repo.Command{Command: "echo hi"}, false},
+		{repo.Command{Script: "echo hi\n", Interpreter: "bash"}, false},
+		{repo.Command{Script: "echo hi\n", Command: "echo hi"}, true},
+		{repo.Command{Script: "echo hi\n", Platforms: map[string]string{"ubuntu": "echo hi"}}, true},
+		{repo.Command{Command: "echo hi", Interpreter: "bash"}, true},
+		{repo.Command{Script: "login {{token}}\n", Args: []repo.ArgumentDef{{Name: "token", Secret: true}}}, false},
+		{repo.Command{Script: "print({{token}})\n", Interpreter: "python3", Args: []repo.ArgumentDef{{Name: "token", Secret: true}}}, true},
+		{repo.Command{Script: "import os\n", Interpreter: "python3", Args: []repo.ArgumentDef{{Name: "token", Secret: true}}}, false},
+	}
+
+	for _, tt := range tests {
+		if err := validateScript(tt.cmd); (err != nil) != tt.wantErr {
+			t.Errorf("validateScript(%+v) error = %v, wantErr %v", tt.cmd, err, tt.wantErr)
+		}
+	}
+}
+
+func TestScriptInterpreter(t *testing.T) {
+	tests := []struct {
+		interpreter string
+		script      string
+		shell       shellSpec
+		want        []string
+	}{
+		{"", "echo hi\n", shellSpec{}, []string{"sh"}},
+		{"", "echo hi\n", shellSpec{name: "bash", options: []string{"pipefail"}}, []string{"bash", "-o", "pipefail"}},
+		{"bash", "echo hi\n", shellSpec{name: "zsh"}, []string{"bash"}},
+		{"python3 -u", "print('hi')\n", shellSpec{name: "zsh"}, []string{"python3", "-u"}},
+		{"#!/usr/bin/env node", "console.log('hi')\n", shellSpec{name: "zsh"}, []string{"/usr/bin/env", "node"}},
+		{"", "#!/bin/bash -e\necho hi\n", shellSpec{name: "zsh"}, []string{"/bin/bash", "-e"}},
+		{"sh", "#!/bin/bash\necho hi\n", shellSpec{name: "zsh"}, []string{"sh"}},
+	}
+
+	for _, tt := range tests {
+		if got := scriptInterpreter(tt.interpreter, tt.script, tt.shell); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("scriptInterpreter(%q, %q, %v) = %q, want %q", tt.interpreter, tt.script, tt.shell, got, tt.want)
+		}
+	}
+}
+
+func TestIsShellInterpreter(t *testing.T) {
+	tests := []struct {
+		interpreter []string
+		want        bool
+	}{
+		{[]string{"sh"}, true},
+		{[]string{"/bin/bash", "-e"}, true},
+		{[]string{"/usr/bin/env", "zsh"}, true},
+		{[]string{"/usr/bin/env", "-S", "bash", "-e"}, true},
+		{[]string{"python3", "-u"}, false},
+		{[]string{"/usr/bin/env", "node"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := isShellInterpreter(tt.interpreter); got != tt.want {
+			t.Errorf("isShellInterpreter(%q) = %v, want %v", tt.interpreter, got, tt.want)
+		}
+	}
+}
+
+func TestScriptHeredoc(t *testing.T) {
+	tests := []struct {
+		interpreter []string
+		script      string
+		want        string
+	}{
+		{[]string{"bash"}, "echo hi\n", "bash <<'EOF'\necho hi\nEOF"},
+		{[]string{"sh"}, "cat <<EOF\nhi\nEOF\n", "sh <<'SHELLDOCK_EOF'\ncat <<EOF\nhi\nEOF\nSHELLDOCK_EOF"},
+		{[]string{"/opt/my tools/run"}, "go\n", "'/opt/my tools/run' <<'EOF'\ngo\nEOF"},
+	}
+
+	for _, tt := range tests {
+		if got := scriptHeredoc(tt.interpreter, tt.script); got != tt.want {
+			t.Errorf("scriptHeredoc(%q, %q) = %q, want %q", tt.interpreter, tt.script, got, tt.want)
+		}
+	}
+}
+
+func TestUnwrapScript(t *testing.T) {
+	cmd := repo.Command{Script: "echo one\n", Interpreter: "bash"}
+
+	script, ok := unwrapScript(cmd, "bash <<'EOF'\necho one\necho two\nEOF")
+	if !ok || script != "echo one\necho two\n" {
+		t.Errorf("unwrapScript() = %q, %v, want the edited script", script, ok)
+	}
+	if _, ok := unwrapScript(cmd, "echo one"); ok {
+		t.Error("unwrapScript() unwrapped a command that is not a here document")
+	}
+	if _, ok := unwrapScript(cmd, "sh <<'EOF'\necho one\nEOF"); ok {
+		t.Error("unwrapScript() unwrapped a script for another interpreter")
+	}
+	if _, ok := unwrapScript(repo.Command{Command: "echo one"}, "bash <<'EOF'\necho one\nEOF"); ok {
+		t.Error("unwrapScript() unwrapped the command of a step without a script")
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell commands")
+	}
+
+	// Quotes and if/else blocks need no escaping in a script
+	script := "name='it'\"'\"'s'\nif [ -n \"$name\" ]; then\n  echo \"$name\"\nelse\n  exit 1\nfi"
+	path, err := writeScript(script)
+	if err != nil {
+		t.Fatalf("writeScript failed: %v", err)
+	}
+	defer os.Remove(path)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		t.Errorf("Expected script permissions 0700, got %o", perm)
+	}
+
+	var capture bytes.Buffer
+	command := scriptCommand(scriptInterpreter("", script, shellSpec{}), path)
+	if err := runWithRetries(processRunner{}, command, retryPolicy{}, terminalStreams, &capture, nil); err != nil {
+		t.Fatalf("running the script failed: %v", err)
+	}
+	if got := strings.TrimSpace(capture.String()); got != "it's" {
+		t.Errorf("Expected script output \"it's\", got %q", got)
+	}
+}
+
+func TestRenderStepScript(t *testing.T) {
+	cmd := repo.Command{Script: "#!/bin/bash\necho {{name}}\n"}
+	rendered, err := renderStep(cmd, "ubuntu", map[string]any{"name": "world"}, false)
+	if err != nil {
+		t.Fatalf("renderStep failed: %v", err)
+	}
+	if rendered.script != "#!/bin/bash\necho world\n" {
+		t.Errorf("Expected the rendered script, got %q", rendered.script)
+	}
+	if want := "/bin/bash <<'EOF'\n#!/bin/bash\necho world\nEOF"; rendered.command != want {
+		t.Errorf("Expected the script shown as %q, got %q", want, rendered.command)
+	}
+
+	// Only shell scripts get quoted values
+	values := map[string]any{"name": "o'neil"}
+	rendered, err = renderStep(repo.Command{Script: "echo {{name}}\n"}, "ubuntu", values, true)
+	if err != nil || rendered.script != "echo 'o'\\''neil'\n" {
+		t.Errorf("Expected the value quoted in a shell script, got %q (%v)", rendered.script, err)
+	}
+	rendered, err = renderStep(repo.Command{Script: "print(\"{{name}}\")\n", Interpreter: "python3"}, "ubuntu", values, true)
+	if err != nil || rendered.script != "print(\"o'neil\")\n" {
+		t.Errorf("Expected the value as it is in a python script, got %q (%v)", rendered.script, err)
+	}
+}
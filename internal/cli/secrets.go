@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shelldock/shelldock/internal/render"
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+// secretEnvPrefix is the prefix of the environment variables that pass the
+// values of secret arguments to commands
+const secretEnvPrefix = "SHELLDOCK_SECRET_"
+
+// secretEnvName returns the environment variable holding a secret argument,
+// e.g. SHELLDOCK_SECRET_DB_PASSWORD for db_password
+func secretEnvName(name string) string {
+	return secretEnvPrefix + strings.ToUpper(argEnvNamePattern.ReplaceAllString(name, "_"))
+}
+
+// secretArgNames returns the names of the commands' secret arguments
+func secretArgNames(commands []repo.Command) map[string]bool {
+	secrets := make(map[string]bool)
+	for _, cmd := range commands {
+		for _, argDef := range cmd.Args {
+			if argDef.IsSecret() {
+				secrets[argDef.Name] = true
+			}
+		}
+	}
+	return secrets
+}
+
+// exportSecrets sets the environment variables holding the values of the
+// commands' secret arguments. Commands inherit them and refer to them instead
+// of having the values in their command line, where other users could see
+// them in the process list.
+func exportSecrets(commands []repo.Command, values map[string]string) error {
+	for _, cmd := range commands {
+		args := stepArgs(cmd, values)
+		for _, argDef := range cmd.Args {
+			if !argDef.IsSecret() || args[argDef.Name] == "" {
+				continue
+			}
+			if err := os.Setenv(secretEnvName(argDef.Name), args[argDef.Name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// redactValues returns template values with secrets replaced by a mask, for
+// rendering commands that are shown rather than run
+func redactValues(values map[string]any) map[string]any {
+	redacted := make(map[string]any, len(values))
+	for name, value := range values {
+		if _, ok := value.(render.Secret); ok {
+			value = render.Redacted(name)
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+// resolveArgSource fetches an argument's value from its source, running source
+// commands with the given shell. A trailing newline, as files and commands
+// usually end with, is not part of the value.
+func resolveArgSource(source repo.ArgumentSource, shell shellSpec) (string, error) {
+	if err := source.Validate(); err != nil {
+		return "", err
+	}
+
+	var value string
+	switch {
+	case source.File != "":
+		path := source.File
+		if path == "~" || strings.HasPrefix(path, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			path = filepath.Join(home, path[1:])
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		value = string(data)
+	case source.Command != "":
+		// The command may ask for a passphrase in the terminal
+		var out bytes.Buffer
+		if err := runInShell(shell, source.Command, 0, os.Stdin, &out, os.Stderr); err != nil {
+			return "", fmt.Errorf("command failed: %w", err)
+		}
+		value = out.String()
+	case source.Env != "":
+		var exists bool
+		if value, exists = os.LookupEnv(source.Env); !exists {
+			return "", fmt.Errorf("environment variable %s is not set", source.Env)
+		}
+	}
+
+	value = strings.TrimRight(value, "\r\n")
+	if value == "" {
+		return "", fmt.Errorf("the value is empty")
+	}
+	return value, nil
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/shelldock/shelldock/internal/render"
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+func TestSecretEnvName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"password", "SHELLDOCK_SECRET_PASSWORD"},
+		{"db_password", "SHELLDOCK_SECRET_DB_PASSWORD"},
+		{"api-token", "SHELLDOCK_SECRET_API_TOKEN"},
+	}
+
+	for _, tt := range tests {
+		if result := secretEnvName(tt.name); result != tt.expected {
+			t.Errorf("secretEnvName(%q) = %q, expected %q", tt.name, result, tt.expected)
+		}
+	}
+}
+
+func TestSecretArgs(t *testing.T) {
+	cmd := repo.Command{
+		Command: "mysql -u {{user}} -p{{password}}",
+		Args: []repo.ArgumentDef{
+			{Name: "user", Default: "root"},
+			{Name: "password", Secret: true},
+		},
+	}
+
+	if secrets := secretArgNames([]repo.Command{cmd}); len(secrets) != 1 || !secrets["password"] {
+		t.Errorf("Expected only 'password' to be secret, got %v", secrets)
+	}
+
+	// The command refers to the environment, and is shown with the value masked
+	values := templateValues(cmd, nil, map[string]string{"password": "hunter2"})
+	rendered, err := renderStep(cmd, "ubuntu", values, true)
+	if err != nil || rendered.command != `mysql -u 'root' -p"${SHELLDOCK_SECRET_PASSWORD}"` {
+		t.Errorf("Unexpected command: %q, %v", rendered.command, err)
+	}
+	shown, err := renderStep(cmd, "ubuntu", redactValues(values), true)
+	if err != nil || shown.command != "mysql -u 'root' -p****" {
+		t.Errorf("Unexpected shown command: %q, %v", shown.command, err)
+	}
+	if _, ok := values["password"].(render.Secret); !ok {
+		t.Error("Expected redactValues to leave the values unchanged")
+	}
+
+	// show and echo leave secrets as placeholders, even with a default
+	cmd.Args[1].Default = "changeme"
+	rendered, err = renderStep(cmd, "ubuntu", showValues([]repo.Command{cmd}, cmd, nil), false)
+	if err != nil || rendered.command != "mysql -u root -p{{password}}" {
+		t.Errorf("Expected a placeholder for the secret, got %q, %v", rendered.command, err)
+	}
+
+	defer os.Unsetenv("SHELLDOCK_SECRET_PASSWORD")
+	if err := exportSecrets([]repo.Command{cmd}, map[string]string{"password": "hunter2"}); err != nil {
+		t.Fatalf("exportSecrets failed: %v", err)
+	}
+	if value := os.Getenv("SHELLDOCK_SECRET_PASSWORD"); value != "hunter2" {
+		t.Errorf("Expected the secret in the environment, got %q", value)
+	}
+}
+
+func TestResolveArgSource(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "db")
+	if err := os.WriteFile(secretFile, []byte("hunter2\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+	emptyFile := filepath.Join(dir, "empty")
+	if err := os.WriteFile(emptyFile, nil, 0600); err != nil {
+		t.Fatalf("Failed to write empty file: %v", err)
+	}
+	t.Setenv("SHELLDOCK_TEST_DB_PASS", "from-env")
+
+	tests := []struct {
+		source   repo.ArgumentSource
+		expected string
+		hasError bool
+	}{
+		{repo.ArgumentSource{File: secretFile}, "hunter2", false},
+		{repo.ArgumentSource{File: filepath.Join(dir, "missing")}, "", true},
+		{repo.ArgumentSource{File: emptyFile}, "", true},
+		{repo.ArgumentSource{Env: "SHELLDOCK_TEST_DB_PASS"}, "from-env", false},
+		{repo.ArgumentSource{Env: "SHELLDOCK_TEST_UNSET"}, "", true},
+		{repo.ArgumentSource{}, "", true},
+		{repo.ArgumentSource{File: secretFile, Env: "SHELLDOCK_TEST_DB_PASS"}, "", true},
+		{repo.ArgumentSource{Command: "printf 'tok\\n'"}, "tok", false},
+		{repo.ArgumentSource{Command: "exit 1"}, "", true},
+	}
+	for _, tt := range tests {
+		if tt.source.Command != "" && runtime.GOOS == "windows" {
+			continue // Commands run with sh
+		}
+		value, err := resolveArgSource(tt.source, shellSpec{})
+		if tt.hasError {
+			if err == nil {
+				t.Errorf("resolveArgSource(%+v) expected error, got %q", tt.source, value)
+			}
+			continue
+		}
+		if err != nil || value != tt.expected {
+			t.Errorf("resolveArgSource(%+v) = %q, %v, expected %q", tt.source, value, err, tt.expected)
+		}
+	}
+
+	// Sourced arguments are fetched when not provided, and are secret
+	commands := []repo.Command{{Args: []repo.ArgumentDef{{Name: "db_pass", From: &repo.ArgumentSource{Env: "SHELLDOCK_TEST_DB_PASS"}}}}}
+	values, err := collectCommandArgs(commands, map[string]string{}, nil)
+	if err != nil || values["db_pass"] != "from-env" {
+		t.Errorf("Expected the value from the source, got %v, %v", values, err)
+	}
+	values, err = collectCommandArgs(commands, map[string]string{"db_pass": "given"}, nil)
+	if err != nil || values["db_pass"] != "given" {
+		t.Errorf("Expected a provided value to win over the source, got %v, %v", values, err)
+	}
+	if !secretArgNames(commands)["db_pass"] {
+		t.Error("Expected a sourced argument to be secret")
+	}
+}
@@ -0,0 +1,288 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shelldock/shelldock/internal/render"
+)
+
+// Session modes of a command set
+const (
+	sessionIsolated   = "isolated"
+	sessionPersistent = "persistent"
+)
+
+// sessionShellLoop reads one line at a time from fd 3 and evaluates it in the
+// current shell, so directory changes and exported variables carry across steps.
+// fd 3 is closed for the evaluated commands so steps cannot read the command stream.
+const sessionShellLoop = `while IFS= read -r __shelldock_line <&3; do eval "$__shelldock_line" 3<&-; done`
+
+// isPersistentSession reports whether a session mode runs all steps in one shell
+func isPersistentSession(mode string) (bool, error) {
+	switch strings.ToLower(mode) {
+	case "", sessionIsolated:
+		return false, nil
+	case sessionPersistent:
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid session '%s' (use isolated or persistent)", mode)
+}
+
+// sessionEndingOptions are shell options that make the shell exit when a
+// command fails or uses an unset variable, which in a session would end the
+// shell along with the directory and environment of earlier steps
+var sessionEndingOptions = map[string]string{"errexit": "e", "nounset": "u"}
+
+// validateSessionShell checks that the shell of a persistent session is not
+// started with options that end it when a step fails, given as shell options
+// or as arguments like -e or -o errexit
+func validateSessionShell(shell shellSpec) error {
+	words := shell.words()
+	for i := 1; i < len(words); i++ {
+		word := words[i]
+		for option, flag := range sessionEndingOptions {
+			short := strings.HasPrefix(word, "-") && !strings.HasPrefix(word, "--") && strings.Contains(word[1:], flag)
+			if short || (word == option && words[i-1] == "-o") {
+				return fmt.Errorf("shell option %s cannot be used with session: persistent, since a failing step would end the session shell", option)
+			}
+		}
+	}
+	return nil
+}
+
+// sessionExitError reports a step that finished with a non-zero exit status in a session
+type sessionExitError struct {
+	code int
+}
+
+func (e *sessionExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// ExitCode returns the exit status of the step
+func (e *sessionExitError) ExitCode() int {
+	return e.code
+}
+
+// shellSession runs steps in one long-lived shell. Each step is written to a
+// script that the shell sources, followed by a marker line carrying the exit
+// status, which tells where the step's output ends.
+type shellSession struct {
+	dir      string    // Directory holding the step scripts
+	shell    shellSpec // Shell running the steps
+	cmd      *exec.Cmd // Running shell, nil after it exited
+	commands *os.File  // Write end of the command pipe (fd 3 of the shell)
+	output   *os.File  // Read end of the shell's stdout
+	pending  []byte    // Output read but not yet passed on
+	marker   []byte    // Marker preceding the exit status of a step
+	scripts  int
+	started  bool
+}
+
+// newShellSession creates a session that keeps its step scripts in dir.
+// The shell itself is started when the first step runs.
+func newShellSession(dir string, shell shellSpec) (*shellSession, error) {
+	if runtime.GOOS == "windows" {
+		return nil, fmt.Errorf("persistent sessions are not supported on Windows")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to create session marker: %w", err)
+	}
+
+	return &shellSession{
+		dir:    dir,
+		shell:  shell,
+		marker: []byte("\036SHELLDOCK:" + hex.EncodeToString(nonce) + ":"),
+	}, nil
+}
+
+// start launches the session shell
+func (s *shellSession) start() error {
+	if s.started {
+		fmt.Fprintln(os.Stderr, "⚠️  Starting a new session shell, directory and environment changes from earlier steps are lost")
+	}
+
+	commandsR, commandsW, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create session pipe: %w", err)
+	}
+	outputR, outputW, err := os.Pipe()
+	if err != nil {
+		commandsR.Close()
+		commandsW.Close()
+		return fmt.Errorf("failed to create session pipe: %w", err)
+	}
+
+	args := s.shell.command(sessionShellLoop)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = outputW
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = []*os.File{commandsR}
+
+	err = cmd.Start()
+	commandsR.Close()
+	outputW.Close()
+	if err != nil {
+		commandsW.Close()
+		outputR.Close()
+		return fmt.Errorf("failed to start session shell: %w", err)
+	}
+
+	s.cmd = cmd
+	s.commands = commandsW
+	s.output = outputR
+	s.pending = nil
+	s.started = true
+	return nil
+}
+
+// run executes a step in the session shell. Timeouts are rejected when the
+// command set is validated, since killing a step would end the session.
+// The session shell is attached to the terminal, so only stdout is taken from the streams.
+func (s *shellSession) run(command string, timeout time.Duration, streams stepStreams) error {
+	if timeout > 0 {
+		return fmt.Errorf("timeouts are not supported in persistent sessions")
+	}
+	return s.exec(command, false, streams.stdout)
+}
+
+// check runs a check in a subshell of the session, so it sees the session's
+// directory and environment without being able to change them
+func (s *shellSession) check(command string, timeout time.Duration) bool {
+	if timeout > 0 {
+		return false
+	}
+	return s.exec(command, true, io.Discard) == nil
+}
+
+// withShell returns the session itself: all steps of a persistent session run
+// in the session's shell, which is checked when the command set is validated
+func (s *shellSession) withShell(shell shellSpec) commandRunner {
+	return s
+}
+
+func (s *shellSession) exec(command string, isolated bool, stdout io.Writer) error {
+	if s.cmd == nil {
+		if err := s.start(); err != nil {
+			return err
+		}
+	}
+
+	s.scripts++
+	script := filepath.Join(s.dir, fmt.Sprintf("step-%d.sh", s.scripts))
+	if err := os.WriteFile(script, []byte(command+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write step script: %w", err)
+	}
+	defer os.Remove(script)
+
+	line := ". " + render.ShellQuote(script)
+	if isolated {
+		line = "( " + line + " ) </dev/null >/dev/null 2>&1"
+	}
+	// printf turns \036 into the marker's leading byte
+	line += fmt.Sprintf("; printf '\\036%s%%d\\n' \"$?\"\n", s.marker[1:])
+
+	if _, err := io.WriteString(s.commands, line); err != nil {
+		return s.exited()
+	}
+
+	code, err := s.readUntilMarker(stdout)
+	if err != nil {
+		return s.exited()
+	}
+	if code != 0 {
+		return &sessionExitError{code: code}
+	}
+	return nil
+}
+
+// readUntilMarker passes the step's output on until the marker line and
+// returns the exit status it carries
+func (s *shellSession) readUntilMarker(stdout io.Writer) (int, error) {
+	buf := make([]byte, 4096)
+	for {
+		if i := bytes.Index(s.pending, s.marker); i >= 0 {
+			if end := bytes.IndexByte(s.pending[i:], '\n'); end >= 0 {
+				_, _ = stdout.Write(s.pending[:i])
+				code, err := strconv.Atoi(string(s.pending[i+len(s.marker) : i+end]))
+				s.pending = append([]byte(nil), s.pending[i+end+1:]...)
+				if err != nil {
+					return -1, fmt.Errorf("invalid session marker: %w", err)
+				}
+				return code, nil
+			}
+		} else {
+			// Hold back only what could be the start of a marker
+			keep := partialMarkerLen(s.pending, s.marker)
+			_, _ = stdout.Write(s.pending[:len(s.pending)-keep])
+			s.pending = append([]byte(nil), s.pending[len(s.pending)-keep:]...)
+		}
+
+		n, err := s.output.Read(buf)
+		s.pending = append(s.pending, buf[:n]...)
+		if err != nil && n == 0 {
+			_, _ = stdout.Write(s.pending)
+			s.pending = nil
+			return -1, err
+		}
+	}
+}
+
+// partialMarkerLen returns the length of the longest suffix of data that is a prefix of marker
+func partialMarkerLen(data, marker []byte) int {
+	for k := len(marker) - 1; k > 0; k-- {
+		if k <= len(data) && bytes.HasSuffix(data, marker[:k]) {
+			return k
+		}
+	}
+	return 0
+}
+
+// exited handles a session shell that ended during a step, e.g. because the
+// step called exit. Its exit status becomes the step's result and the next
+// step starts a new shell.
+func (s *shellSession) exited() error {
+	s.commands.Close()
+	err := s.cmd.Wait()
+	s.output.Close()
+	s.cmd = nil
+
+	code := exitCodeOf(err)
+	fmt.Fprintln(os.Stderr, "⚠️  The session shell exited")
+	if code != 0 {
+		return &sessionExitError{code: code}
+	}
+	return nil
+}
+
+// close ends the session shell and removes the step scripts
+func (s *shellSession) close() error {
+	defer os.RemoveAll(s.dir)
+	if s.cmd == nil {
+		return nil
+	}
+
+	// The shell loop exits once the command pipe is closed
+	s.commands.Close()
+	err := s.cmd.Wait()
+	s.output.Close()
+	s.cmd = nil
+	return err
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/shelldock/shelldock/internal/render"
+)
+
+func TestShellSession(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("persistent sessions are not supported on Windows")
+	}
+
+	dir := t.TempDir()
+	session, err := newShellSession(filepath.Join(dir, "session"), shellSpec{})
+	if err != nil {
+		t.Fatalf("newShellSession failed: %v", err)
+	}
+	defer session.close()
+
+	// Directory changes and exported variables carry across steps
+	if err := session.run("cd "+render.ShellQuote(dir)+" && export GREETING=hello", 0, stepStreams{stdout: &bytes.Buffer{}}); err != nil {
+		t.Fatalf("first step failed: %v", err)
+	}
+	var out bytes.Buffer
+	if err := session.run("pwd\nprintf '%s' \"$GREETING\"", 0, stepStreams{stdout: &out}); err != nil {
+		t.Fatalf("second step failed: %v", err)
+	}
+	resolved, _ := filepath.EvalSymlinks(dir)
+	if got := out.String(); !strings.HasPrefix(got, resolved+"\n") && !strings.HasPrefix(got, dir+"\n") {
+		t.Errorf("Expected working directory %s, got %q", dir, got)
+	}
+	if !strings.HasSuffix(out.String(), "\nhello") {
+		t.Errorf("Expected output without a trailing newline to end with 'hello', got %q", out.String())
+	}
+
+	// Exit statuses are reported per step
+	err = session.run("false", 0, stepStreams{stdout: &bytes.Buffer{}})
+	if code := exitCodeOf(err); code != 1 {
+		t.Errorf("Expected exit code 1, got %d (%v)", code, err)
+	}
+
+	// Checks see the session state but cannot change it
+	if !session.check("test \"$GREETING\" = hello && cd /", 0) {
+		t.Error("Expected check to see the session environment")
+	}
+	out.Reset()
+	if err := session.run("printf '%s' \"$PWD\"", 0, stepStreams{stdout: &out}); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	if out.String() == "/" {
+		t.Error("Expected check to not change the session directory")
+	}
+
+	// A step that exits ends the shell, and the next step starts a new one
+	err = session.run("exit 3", 0, stepStreams{stdout: &bytes.Buffer{}})
+	if code := exitCodeOf(err); code != 3 {
+		t.Errorf("Expected exit code 3, got %d (%v)", code, err)
+	}
+	out.Reset()
+	if err := session.run("echo ${GREETING:-reset}", 0, stepStreams{stdout: &out}); err != nil {
+		t.Fatalf("step after exit failed: %v", err)
+	}
+	if out.String() != "reset\n" {
+		t.Errorf("Expected a fresh session, got %q", out.String())
+	}
+}
+
+func TestIsPersistentSession(t *testing.T) {
+	tests := []struct {
+		mode       string
+		persistent bool
+		hasError   bool
+	}{
+		{"", false, false},
+		{"isolated", false, false},
+		{"persistent", true, false},
+		{"Persistent", true, false},
+		{"shared", false, true},
+	}
+
+	for _, tt := range tests {
+		persistent, err := isPersistentSession(tt.mode)
+		if tt.hasError {
+			if err == nil {
+				t.Errorf("isPersistentSession(%q) expected error, got nil", tt.mode)
+			}
+			continue
+		}
+		if err != nil || persistent != tt.persistent {
+			t.Errorf("isPersistentSession(%q) = %v, %v, expected %v", tt.mode, persistent, err, tt.persistent)
+		}
+	}
+}
+
+func TestValidateSessionShell(t *testing.T) {
+	tests := []struct {
+		shell shellSpec
+		valid bool
+	}{
+		{shellSpec{}, true},
+		{shellSpec{name: "bash", options: []string{"pipefail"}}, true},
+		{shellSpec{name: "bash --noprofile --norc"}, true},
+		{shellSpec{name: "bash", options: []string{"pipefail", "errexit"}}, false},
+		{shellSpec{name: "zsh", options: []string{"nounset"}}, false},
+		{shellSpec{name: "bash -e"}, false},
+		{shellSpec{name: "bash -eu"}, false},
+		{shellSpec{name: "bash -o errexit"}, false},
+	}
+
+	for _, tt := range tests {
+		err := validateSessionShell(tt.shell)
+		if tt.valid && err != nil {
+			t.Errorf("validateSessionShell(%q) unexpected error: %v", tt.shell, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("validateSessionShell(%q) expected error, got nil", tt.shell)
+		}
+	}
+}
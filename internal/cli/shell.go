@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/shelldock/shelldock/internal/config"
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+// shellOptionPattern matches the name of a shell option as given to set -o,
+// e.g. pipefail
+var shellOptionPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// shellSpec is the shell that runs commands and the options it is started
+// with. The zero value is the default shell without options.
+type shellSpec struct {
+	name    string   // Program, possibly with arguments (e.g., "bash --noprofile")
+	options []string // Options turned on with -o (e.g., errexit, pipefail)
+}
+
+// words returns the program, arguments and options that start the shell
+func (s shellSpec) words() []string {
+	words := strings.Fields(s.name)
+	if len(words) == 0 {
+		words = []string{config.DefaultShell}
+	}
+	for _, option := range s.options {
+		words = append(words, "-o", option)
+	}
+	return words
+}
+
+// command returns the program and arguments that run a command in the shell
+func (s shellSpec) command(command string) []string {
+	return append(s.words(), "-c", command)
+}
+
+// isDefault reports whether the shell is the default shell without options
+func (s shellSpec) isDefault() bool {
+	return len(s.options) == 0 && strings.Join(s.words(), " ") == config.DefaultShell
+}
+
+func (s shellSpec) String() string {
+	return shellWords(s.words())
+}
+
+func (s shellSpec) equal(other shellSpec) bool {
+	return s.String() == other.String()
+}
+
+// inheritShell returns the shell of a level that may name a shell and options.
+// Options go with the shell they are given for: a level naming a shell uses
+// only its own options, while a level giving only options applies them to the
+// inherited shell.
+func inheritShell(name string, options []string, inherited shellSpec) shellSpec {
+	if strings.TrimSpace(name) != "" {
+		return shellSpec{name: name, options: options}
+	}
+	if options != nil {
+		return shellSpec{name: inherited.name, options: options}
+	}
+	return inherited
+}
+
+// setShell returns the shell that runs the commands of a set: its own, else
+// the default from the config
+func setShell(cmdSet *repo.CommandSet) shellSpec {
+	name, options, err := config.GetShell()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to get the default shell: %v, using %s\n", err, config.DefaultShell)
+	}
+	return inheritShell(cmdSet.Shell, cmdSet.ShellOptions, shellSpec{name: name, options: options})
+}
+
+// withShells returns commands with the shell each step runs in filled in: the
+// step's own, else the set's
+func withShells(commands []repo.Command, shell shellSpec) []repo.Command {
+	filled := make([]repo.Command, len(commands))
+	for i, cmd := range commands {
+		spec := inheritShell(cmd.Shell, cmd.ShellOptions, shell)
+		cmd.Shell, cmd.ShellOptions = spec.name, spec.options
+		filled[i] = cmd
+	}
+	return filled
+}
+
+// commandShell returns the shell that runs a step's commands, as filled in by
+// withShells. Steps without one run in the default shell.
+func commandShell(cmd repo.Command) shellSpec {
+	return shellSpec{name: cmd.Shell, options: cmd.ShellOptions}
+}
+
+// optionShells are the shells known to take the options of set -o, such as
+// pipefail, on the command line. sh is dash on Debian and Ubuntu, which doesn't
+// know pipefail.
+var optionShells = map[string]bool{"bash": true, "zsh": true, "ksh": true, "mksh": true}
+
+// validateShell checks the options of a shell, which can only be given for a
+// shell known to take them, named explicitly
+func validateShell(shell shellSpec) error {
+	if len(shell.options) == 0 {
+		return nil
+	}
+	words := strings.Fields(shell.name)
+	if len(words) == 0 || !optionShells[path.Base(words[0])] {
+		name := config.DefaultShell
+		if len(words) > 0 {
+			name = words[0]
+		}
+		return fmt.Errorf("shell options need a shell that takes them, like bash, zsh or ksh, not %s", name)
+	}
+	for _, option := range shell.options {
+		if !shellOptionPattern.MatchString(option) {
+			return fmt.Errorf("invalid shell option '%s' (use names like errexit or pipefail)", option)
+		}
+	}
+	return nil
+}
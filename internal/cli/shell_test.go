@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"io"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+func TestShellSpecCommand(t *testing.T) {
+	tests := []struct {
+		shell shellSpec
+		want  []string
+	}{
+		{shellSpec{}, []string{"sh", "-c", "echo hi"}},
+		{shellSpec{name: "bash", options: []string{"errexit", "pipefail"}}, []string{"bash", "-o", "errexit", "-o", "pipefail", "-c", "echo hi"}},
+		{shellSpec{name: "bash --noprofile"}, []string{"bash", "--noprofile", "-c", "echo hi"}},
+		{shellSpec{options: []string{"nounset"}}, []string{"sh", "-o", "nounset", "-c", "echo hi"}},
+	}
+
+	for _, tt := range tests {
+		if got := tt.shell.command("echo hi"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v.command() = %q, want %q", tt.shell, got, tt.want)
+		}
+	}
+
+	if !(shellSpec{name: "sh"}).isDefault() || (shellSpec{name: "bash"}).isDefault() || (shellSpec{options: []string{"errexit"}}).isDefault() {
+		t.Error("isDefault() should only hold for sh without options")
+	}
+}
+
+func TestInheritShell(t *testing.T) {
+	set := shellSpec{name: "bash", options: []string{"pipefail"}}
+	tests := []struct {
+		name    string
+		options []string
+		want    shellSpec
+	}{
+		{"", nil, set},
+		{"zsh", nil, shellSpec{name: "zsh"}},
+		{"zsh", []string{"errexit"}, shellSpec{name: "zsh", options: []string{"errexit"}}},
+		{"", []string{"errexit"}, shellSpec{name: "bash", options: []string{"errexit"}}},
+		{"", []string{}, shellSpec{name: "bash", options: []string{}}},
+	}
+
+	for _, tt := range tests {
+		if got := inheritShell(tt.name, tt.options, set); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("inheritShell(%q, %v) = %+v, want %+v", tt.name, tt.options, got, tt.want)
+		}
+	}
+}
+
+func TestWithShells(t *testing.T) {
+	commands := []repo.Command{
+		{Command: "echo one"},
+		{Command: "echo two", Shell: "sh"},
+	}
+	filled := withShells(commands, shellSpec{name: "bash", options: []string{"pipefail"}})
+
+	if got := commandShell(filled[0]).String(); got != "bash -o pipefail" {
+		t.Errorf("Expected the set's shell for step 1, got %q", got)
+	}
+	if got := commandShell(filled[1]).String(); got != "sh" {
+		t.Errorf("Expected the step's own shell for step 2, got %q", got)
+	}
+	if commands[0].Shell != "" {
+		t.Error("withShells modified the original commands")
+	}
+}
+
+func TestValidateShell(t *testing.T) {
+	if err := validateShell(shellSpec{name: "bash", options: []string{"errexit", "pipefail"}}); err != nil {
+		t.Errorf("validateShell() unexpected error: %v", err)
+	}
+	for _, option := range []string{"", "-e", "pipe fail", "Errexit"} {
+		if err := validateShell(shellSpec{name: "bash", options: []string{option}}); err == nil {
+			t.Errorf("validateShell() with option %q expected error, got nil", option)
+		}
+	}
+
+	// Options need a shell known to take them
+	for _, shell := range []shellSpec{{name: "sh"}, {name: "dash"}, {}} {
+		if err := validateShell(shellSpec{name: shell.name, options: []string{"pipefail"}}); err == nil {
+			t.Errorf("validateShell() with options for %q expected error, got nil", shell.name)
+		}
+	}
+	for _, name := range []string{"/bin/bash", "zsh", "ksh --norc"} {
+		if err := validateShell(shellSpec{name: name, options: []string{"pipefail"}}); err != nil {
+			t.Errorf("validateShell() with options for %q unexpected error: %v", name, err)
+		}
+	}
+	if err := validateShell(shellSpec{name: "sh"}); err != nil {
+		t.Errorf("validateShell() without options unexpected error: %v", err)
+	}
+}
+
+func TestRunInShellPipefail(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell commands")
+	}
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not installed")
+	}
+
+	// A pipeline only fails on an earlier command with pipefail
+	if err := runInShell(shellSpec{name: "bash"}, "false | true", 0, nil, io.Discard, io.Discard); err != nil {
+		t.Errorf("Expected the pipeline to succeed without pipefail, got %v", err)
+	}
+	if err := runInShell(shellSpec{name: "bash", options: []string{"pipefail"}}, "false | true", 0, nil, io.Discard, io.Discard); err == nil {
+		t.Error("Expected the pipeline to fail with pipefail")
+	}
+	if !(processRunner{}).withShell(shellSpec{name: "bash"}).check(`[[ -n "$BASH_VERSION" ]]`, 0) {
+		t.Error("Expected the check to run in bash")
+	}
+	if value, err := resolveArgSource(repo.ArgumentSource{Command: `[[ -n "$BASH_VERSION" ]] && echo bash`}, shellSpec{name: "bash"}); err != nil || value != "bash" {
+		t.Errorf("Expected the source command to run in bash, got %q, %v", value, err)
+	}
+}
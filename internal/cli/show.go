@@ -14,13 +14,17 @@ import (
 var (
 	showLocalFlag bool
 	showVersionFlag string
+	showCheckFlag bool
 )
 
 var showCmd = &cobra.Command{
 	Use:   "show [command-set-name]",
 	Short: "Show commands in a command set without executing",
 	Long: `Show the commands in a command set without executing them.
-Useful for previewing what commands will be run.`,
+Useful for previewing what commands will be run.
+
+Use --check to evaluate each step's check command and see which
+steps are already satisfied and would be skipped.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
@@ -54,16 +58,89 @@ Useful for previewing what commands will be run.`,
 		fmt.Printf("📝 Description: %s\n", cmdSet.Description)
 		fmt.Printf("🔢 Version: %s\n", cmdSet.Version)
 		fmt.Printf("🖥️  Platform: %s\n", platform)
+		if persistent, err := isPersistentSession(cmdSet.Session); err != nil {
+			fmt.Printf("⚠️  %v\n", err)
+		} else if persistent {
+			fmt.Printf("🐚 Session: persistent (steps share one shell)\n")
+		}
+		shell := setShell(cmdSet)
+		if !shell.isDefault() {
+			fmt.Printf("🐚 Shell: %s\n", shell)
+		}
+		if len(cmdSet.Args) > 0 {
+			fmt.Printf("📝 Arguments:\n")
+			for _, argDef := range cmdSet.Args {
+				desc := argDef.Name
+				if len(argDef.Choices) > 0 {
+					desc = fmt.Sprintf("%s [%s]", desc, strings.Join(argDef.Choices, "|"))
+				} else if argDef.Type != "" {
+					desc = fmt.Sprintf("%s <%s>", desc, argDef.Type)
+				}
+				if argDef.Default != "" && argDef.IsSecret() {
+					desc = fmt.Sprintf("%s (default: %s)", desc, redactedValue)
+				} else if argDef.DefaultFrom != "" {
+					desc = fmt.Sprintf("%s (default: output of %s)", desc, argDef.DefaultFrom)
+				} else if argDef.Default != "" {
+					desc = fmt.Sprintf("%s (default: %s)", desc, argDef.Default)
+				} else if argDef.Required {
+					desc = fmt.Sprintf("%s (required)", desc)
+				}
+				if from := argDef.From; from != nil {
+					switch {
+					case from.File != "":
+						desc = fmt.Sprintf("%s (from file %s)", desc, from.File)
+					case from.Command != "":
+						desc = fmt.Sprintf("%s (from command: %s)", desc, from.Command)
+					case from.Env != "":
+						desc = fmt.Sprintf("%s (from $%s)", desc, from.Env)
+					}
+				} else if argDef.Secret {
+					desc = fmt.Sprintf("%s (secret)", desc)
+				}
+				fmt.Printf("   • %s\n", desc)
+			}
+		}
 		fmt.Printf("📋 Commands:\n\n")
 
 		hasUnsupportedCommands := false
-		for i, cmd := range cmdSet.Commands {
-			fmt.Printf("  %d. %s\n", i+1, cmd.Description)
+		commands := withShells(withSharedArgs(cmdSet), shell)
+		facts := builtinFacts(platform, "")
+		for i, cmd := range commands {
+			fmt.Printf("  %s\n", stepHeading(cmd, i+1))
+			if len(cmd.DependsOn) > 0 {
+				fmt.Printf("     🔗 Depends on: %s\n", strings.Join(cmd.DependsOn, ", "))
+			}
 			
-			// Show platform-specific command if available
+			// Show platform-specific command if available, with argument defaults filled in
 			command := getCommandForPlatformShow(cmd, platform)
+			rendered, renderErr := renderStep(cmd, platform, showValues(commands, cmd, facts), quotesArgs(cmdSet))
+			if renderErr != nil {
+				rendered = renderedStep{command: command, check: getCheckForPlatform(cmd, platform), undo: getUndoForPlatform(cmd, platform)}
+			}
 			if command != "" {
-				fmt.Printf("     $ %s\n", command)
+				fmt.Printf("     $ %s\n", indentCommand(rendered.command, "       "))
+				if renderErr != nil {
+					fmt.Printf("     ⚠️  %v\n", renderErr)
+				}
+				if stepShell := commandShell(cmd); !stepShell.equal(shell) {
+					fmt.Printf("     🐚 Shell: %s\n", stepShell)
+				}
+				if err := validateShell(commandShell(cmd)); err != nil {
+					fmt.Printf("     ⚠️  %v\n", err)
+				}
+				if cmd.When != "" {
+					fmt.Printf("     ❓ When: %s\n", cmd.When)
+				}
+				if check := rendered.check; check != "" {
+					fmt.Printf("     🔍 Check: %s\n", check)
+					if showCheckFlag && !strings.Contains(check, "{{") {
+						if (processRunner{shell: commandShell(cmd)}).check(check, 0) {
+							fmt.Printf("     ✔️  Already satisfied (would be skipped)\n")
+						} else {
+							fmt.Printf("     ✏️  Not satisfied (would run)\n")
+						}
+					}
+				}
 			} else {
 				fmt.Printf("     ⚠️  No command available for platform '%s'\n", platform)
 				if cmd.Platforms != nil {
@@ -76,6 +153,16 @@ Useful for previewing what commands will be run.`,
 				hasUnsupportedCommands = true
 			}
 			
+			if undo := rendered.undo; undo != "" {
+				fmt.Printf("     ↩️  Undo: %s\n", undo)
+			}
+
+			if policy, err := parseRetryPolicy(cmd); err != nil {
+				fmt.Printf("     ⚠️  %v\n", err)
+			} else if desc := policy.String(); desc != "" {
+				fmt.Printf("     ⏱️  %s\n", desc)
+			}
+
 			if cmd.SkipOnError {
 				fmt.Printf("     ⚠️  (skip_on_error: true)\n")
 			}
@@ -105,7 +192,10 @@ func getCommandForPlatformShow(cmd repo.Command, platform string) string {
 		}
 	}
 
-	// Fallback to generic command
+	// Fallback to generic command, or the script
+	if cmd.Script != "" {
+		return cmd.Script
+	}
 	return cmd.Command
 }
 
@@ -113,5 +203,6 @@ func init() {
 	showCmd.Flags().BoolVarP(&showLocalFlag, "local", "l", false, "Only check local repository (skip bundled repository)")
 	showCmd.Flags().StringVar(&showVersionFlag, "ver", "", "Show specific version or tag (default: latest). Can also use name@version format")
 	showCmd.Flags().StringVar(&showVersionFlag, "version", "", "Show specific version or tag (default: latest) - alias for --ver")
+	showCmd.Flags().BoolVar(&showCheckFlag, "check", false, "Run check commands to show which steps are already satisfied")
 }
 
@@ -0,0 +1,276 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+// plannedStep is a step of a run with everything needed to execute it
+type plannedStep struct {
+	cmd     repo.Command
+	num     int // Step number (1-indexed) in the command set
+	order   int // Position (1-indexed) in which the step was started
+	label   string
+	policy  retryPolicy
+	deps    []int             // Indices of the planned steps this step waits for
+	args    map[string]string // Argument values, resolved before the run starts
+	removed bool              // Removed from the plan with --edit
+}
+
+// stepFailure is a step that failed without skip_on_error
+type stepFailure struct {
+	num    int
+	err    error
+	result stepResult
+}
+
+// runState is the state shared by the steps of a run. Steps may run
+// concurrently, so the checkpoint is only accessed under the lock.
+type runState struct {
+	mu           sync.Mutex
+	checkpoint   *runCheckpoint
+	platform     string
+	facts        map[string]string // Built-in template variables
+	runner       commandRunner
+	providedArgs map[string]string
+	quote        bool // Shell-quote substituted values
+	total        int
+	reporter     runReporter
+	stepper      *stepper // Asks before each step in --step mode, nil otherwise
+}
+
+// executeStep runs one step: it evaluates the step's condition and check, runs
+// its command with retries and records the result in the checkpoint. It returns
+// the result and an error if the step failed and the run must stop.
+func (s *runState) executeStep(step *plannedStep) (stepResult, error) {
+	cmd := step.cmd
+	report := s.reporter.step(step, s.total)
+	defer report.close()
+
+	if step.removed {
+		report.started()
+		report.skipped(skipEdited, "")
+		result := stepResult{Status: stepSkipped}
+		s.complete(step, result)
+		return result, nil
+	}
+
+	command := getCommandForPlatform(cmd, s.platform)
+	if command == "" {
+		report.started()
+		report.skipped(skipPlatform, s.platform)
+		result := stepResult{Status: stepSkipped}
+		s.complete(step, result)
+		return result, nil
+	}
+
+	s.mu.Lock()
+	// Render the step with its arguments and registered variables
+	stepValues := templateValues(cmd, s.facts, s.checkpoint.Vars, step.args)
+	var whenFacts map[string]string
+	if cmd.When != "" {
+		whenArgs := mergeValues(s.providedArgs, s.checkpoint.Args, step.args)
+		whenFacts = buildWhenFacts(s.platform, whenArgs, s.checkpoint.Vars, s.checkpoint.Results)
+	}
+	s.mu.Unlock()
+	rendered, err := renderStep(cmd, s.platform, stepValues, s.quote)
+	var shown renderedStep
+	if err == nil {
+		// Commands are shown with the values of secrets masked
+		shown, err = renderStep(cmd, s.platform, redactValues(stepValues), s.quote)
+	}
+
+	report.started()
+	if err != nil {
+		result := stepResult{Status: stepFailed, ExitCode: -1}
+		report.failed(result, 0, err)
+		return result, err
+	}
+	command = rendered.command
+	runner := s.runner.withShell(commandShell(cmd))
+
+	// Skip the step when its condition is not met
+	if cmd.When != "" {
+		ok, err := evaluateWhen(cmd.When, whenFacts)
+		if err != nil {
+			result := stepResult{Status: stepFailed, ExitCode: -1}
+			report.failed(result, 0, err)
+			return result, err
+		}
+		if !ok {
+			report.skipped(skipWhen, cmd.When)
+			result := stepResult{Status: stepSkipped}
+			s.complete(step, result)
+			return result, nil
+		}
+	}
+
+	// Skip the step when its check reports it is already satisfied
+	if check := rendered.check; check != "" {
+		if runner.check(check, step.policy.timeout) {
+			report.skipped(skipCheck, shown.check)
+			result := stepResult{Status: stepSatisfied}
+			s.complete(step, result)
+			return result, nil
+		}
+	}
+
+	report.command(shown.command)
+
+	// In --step mode the user decides whether and how the step runs
+	if s.stepper != nil {
+		choice, edited := s.stepper.beforeStep(step, command, shown, report)
+		switch choice {
+		case stepSkip:
+			report.skipped(skipUser, "")
+			result := stepResult{Status: stepSkipped}
+			s.complete(step, result)
+			return result, nil
+		case stepAbort:
+			result := stepResult{Status: stepFailed, ExitCode: -1}
+			report.failed(result, 0, errAborted)
+			return result, errAborted
+		}
+		command = edited
+	}
+
+	// Scripts run from a file, unless the user replaced them in --step mode
+	if rendered.script != "" && command == rendered.command {
+		path, err := writeScript(rendered.script)
+		if err != nil {
+			result := stepResult{Status: stepFailed, ExitCode: -1}
+			report.failed(result, 0, err)
+			return result, err
+		}
+		defer os.Remove(path)
+		command = scriptCommand(rendered.interp, path)
+	}
+
+	// Capture stdout for steps that register a variable
+	var capture *bytes.Buffer
+	if cmd.Register != "" {
+		capture = &bytes.Buffer{}
+	}
+
+	start := time.Now()
+	skipFailure := cmd.SkipOnError
+	for {
+		err = runWithRetries(runner, command, step.policy, report.streams(), capture, report.retrying)
+		if err == nil || s.stepper == nil || cmd.SkipOnError {
+			break
+		}
+		choice := s.stepper.afterFailure(err)
+		if choice != stepRetry {
+			skipFailure = choice == stepSkip
+			break
+		}
+	}
+	duration := time.Since(start)
+	if capture != nil {
+		s.mu.Lock()
+		s.checkpoint.Vars[cmd.Register] = strings.TrimSpace(capture.String())
+		s.mu.Unlock()
+	}
+	if err != nil {
+		result := stepResult{Status: stepFailed, ExitCode: exitCodeOf(err), DurationMS: duration.Milliseconds()}
+		if skipFailure {
+			report.finished(result, duration, err)
+			s.complete(step, result)
+			return result, nil
+		}
+		report.failed(result, duration, err)
+		return result, err
+	}
+
+	result := stepResult{Status: stepSuccess, DurationMS: duration.Milliseconds()}
+	s.complete(step, result)
+	report.finished(result, duration, nil)
+	return result, nil
+}
+
+// complete records a finished step. Skipped steps register an empty value.
+func (s *runState) complete(step *plannedStep, result stepResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if step.cmd.Register != "" && result.Status != stepSuccess && result.Status != stepFailed {
+		s.checkpoint.Vars[step.cmd.Register] = ""
+	}
+	saveCheckpoint(s.checkpoint.markCompleted(step.num, result))
+}
+
+// runSequential runs steps one after another and stops at the first failure
+func (s *runState) runSequential(steps []*plannedStep) []stepFailure {
+	for i, step := range steps {
+		step.order = i + 1
+		if result, err := s.executeStep(step); err != nil {
+			return []stepFailure{{num: step.num, err: err, result: result}}
+		}
+	}
+	return nil
+}
+
+// runParallel runs each step once its dependencies finished, at most jobs at a
+// time. The reporter keeps the output of the steps apart and steps get no
+// terminal input. After a step fails, no new steps are started and running ones are
+// waited for.
+func (s *runState) runParallel(steps []*plannedStep, jobs int) []stepFailure {
+	type finished struct {
+		index  int
+		result stepResult
+		err    error
+	}
+
+	done := make(chan finished)
+	waiting := make([]int, len(steps))
+	dependents := make([][]int, len(steps))
+	var ready []int
+	for i, step := range steps {
+		waiting[i] = len(step.deps)
+		for _, dep := range step.deps {
+			dependents[dep] = append(dependents[dep], i)
+		}
+		if waiting[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	var failures []stepFailure
+	running, started := 0, 0
+	for {
+		for len(failures) == 0 && running < jobs && len(ready) > 0 {
+			index := ready[0]
+			ready = ready[1:]
+			running++
+			started++
+			steps[index].order = started
+
+			go func(index int) {
+				result, err := s.executeStep(steps[index])
+				done <- finished{index: index, result: result, err: err}
+			}(index)
+		}
+		if running == 0 {
+			return failures
+		}
+
+		f := <-done
+		running--
+		if f.err != nil {
+			failures = append(failures, stepFailure{num: steps[f.index].num, err: f.err, result: f.result})
+			continue
+		}
+		for _, dependent := range dependents[f.index] {
+			waiting[dependent]--
+			if waiting[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+		sort.Ints(ready) // Start ready steps in command set order
+	}
+}
@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"sync"
+
+	"github.com/shelldock/shelldock/internal/config"
+	"github.com/shelldock/shelldock/internal/render"
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+// renderedStep is a step's command, check and undo command for a platform
+// with their templates rendered
+type renderedStep struct {
+	command string
+	script  string   // Rendered script of a step with one; command then shows it
+	interp  []string // Interpreter running the script
+	check   string
+	undo    string
+}
+
+// renderStep renders the templates of a step for a platform
+func renderStep(cmd repo.Command, platform string, values map[string]any, quote bool) (renderedStep, error) {
+	var rendered renderedStep
+	var err error
+	commandQuote := quote
+	if cmd.Script != "" {
+		rendered.interp = scriptInterpreter(cmd.Interpreter, cmd.Script, commandShell(cmd))
+		commandQuote = quote && isShellInterpreter(rendered.interp)
+	}
+	if rendered.command, err = render.Render(getCommandForPlatform(cmd, platform), values, commandQuote); err != nil {
+		return rendered, err
+	}
+	if cmd.Script != "" {
+		rendered.script = rendered.command
+		rendered.command = scriptHeredoc(rendered.interp, rendered.script)
+	}
+	if rendered.check, err = render.Render(getCheckForPlatform(cmd, platform), values, quote); err != nil {
+		return rendered, err
+	}
+	if rendered.undo, err = render.Render(getUndoForPlatform(cmd, platform), values, quote); err != nil {
+		return rendered, err
+	}
+	return rendered, nil
+}
+
+// runDirFact is the built-in variable holding the run's directory, which is
+// only known once the run starts
+const runDirFact = "shelldock_run_dir"
+
+var (
+	hostFactsOnce sync.Once
+	hostFacts     map[string]string
+)
+
+// builtinFacts returns the built-in variables available to every template: the
+// platform commands are chosen for, facts detected about the host and the
+// run's directory, if known
+func builtinFacts(platform, runDir string) map[string]string {
+	hostFactsOnce.Do(func() {
+		hostFacts = config.DetectFacts()
+	})
+
+	facts := make(map[string]string, len(hostFacts)+2)
+	for name, value := range hostFacts {
+		facts[name] = value
+	}
+	facts["platform"] = platform
+	if runDir != "" {
+		facts[runDirFact] = runDir
+	}
+	return facts
+}
+
+// templateValues returns the values available to a step's templates: the
+// built-in facts, the given values, then the step's arguments, using their
+// defaults for arguments without a value. Arguments shadow facts of the same
+// name, even without a value. Arguments without a value or default render
+// empty, the values of list arguments are split into items, and secrets refer
+// to the environment variables holding them.
+func templateValues(cmd repo.Command, facts map[string]string, values ...map[string]string) map[string]any {
+	merged := mergeValues(values...)
+	result := make(map[string]any, len(facts)+len(merged)+len(cmd.Args))
+	for name, value := range facts {
+		result[name] = value
+	}
+	for name, value := range merged {
+		result[name] = value
+	}
+	for _, argDef := range cmd.Args {
+		value := merged[argDef.Name]
+		if value == "" {
+			value = staticDefault(argDef)
+		}
+		if argDef.IsSecret() && value != "" {
+			// Secrets reach commands through the environment (see exportSecrets)
+			result[argDef.Name] = render.Secret(secretEnvName(argDef.Name))
+		} else if argDef.Type == repo.ArgTypeList {
+			result[argDef.Name] = render.SplitList(value)
+		} else {
+			result[argDef.Name] = value
+		}
+	}
+	return result
+}
+
+// withPendingPlaceholders adds placeholders for the values that are not known
+// before the run starts: the run's directory and the output that steps register
+func withPendingPlaceholders(commands []repo.Command, values map[string]any) map[string]any {
+	if _, exists := values[runDirFact]; !exists {
+		values[runDirFact] = render.Placeholder(runDirFact)
+	}
+	for _, cmd := range commands {
+		if _, exists := values[cmd.Register]; cmd.Register != "" && !exists {
+			values[cmd.Register] = render.Placeholder(cmd.Register)
+		}
+	}
+	return values
+}
+
+// showValues returns the values of a step's templates when nothing has been
+// provided: built-in facts, argument defaults, and placeholders for secrets and
+// everything else
+func showValues(commands []repo.Command, cmd repo.Command, facts map[string]string) map[string]any {
+	values := templateValues(cmd, facts)
+	for _, argDef := range cmd.Args {
+		if staticDefault(argDef) == "" || argDef.IsSecret() {
+			values[argDef.Name] = render.Placeholder(argDef.Name)
+		}
+	}
+	return withPendingPlaceholders(commands, values)
+}
@@ -0,0 +1,333 @@
+package cli
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/shelldock/shelldock/internal/config"
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+// when expressions decide whether a step runs. They compare facts about the
+// host, argument values and results of earlier steps, e.g.:
+//
+//	args.enable_ssl == 'yes' && arch != 'arm64'
+//	steps.2.status == 'success' || !args.skip_tests
+//
+// Available facts are platform, distro, os, arch, args.<name>, vars.<name>
+// for registered step output, and steps.<n>.status (success, failed, skipped or satisfied) and
+// steps.<n>.exit_code for earlier steps. Supported operators are ==, !=, !,
+// && and || with parentheses for grouping. A bare value is true unless it
+// is empty, "false", "no", "off" or "0".
+
+// whenNamespaces are identifier prefixes that resolve to "" when the value is unknown
+var whenNamespaces = []string{"args.", "vars.", "steps."}
+
+type whenTokenKind int
+
+const (
+	whenIdent whenTokenKind = iota
+	whenString
+	whenOp
+	whenEOF
+)
+
+type whenToken struct {
+	kind  whenTokenKind
+	value string
+}
+
+// buildWhenFacts returns the facts when expressions are evaluated against
+func buildWhenFacts(platform string, args, vars map[string]string, results map[int]stepResult) map[string]string {
+	facts := map[string]string{
+		"platform": platform,
+		"distro":   config.DetectDistribution(),
+		"os":       runtime.GOOS,
+		"arch":     config.DetectArch(),
+	}
+	for name, value := range args {
+		facts["args."+name] = value
+	}
+	for name, value := range vars {
+		facts["vars."+name] = value
+	}
+	for step, result := range results {
+		facts[fmt.Sprintf("steps.%d.status", step)] = result.Status
+		facts[fmt.Sprintf("steps.%d.exit_code", step)] = strconv.Itoa(result.ExitCode)
+	}
+	return facts
+}
+
+// whenNames are the registered variables and steps of a command set that
+// when expressions can refer to
+type whenNames struct {
+	vars  map[string]bool
+	steps int
+}
+
+// newWhenNames collects the variables and steps of a command set
+func newWhenNames(cmdSet *repo.CommandSet) whenNames {
+	names := whenNames{vars: make(map[string]bool), steps: len(cmdSet.Commands)}
+	for _, cmd := range cmdSet.Commands {
+		if cmd.Register != "" {
+			names.vars[cmd.Register] = true
+		}
+	}
+	return names
+}
+
+// checkWhenNames reports the first name under args., vars. or steps. in a
+// step's when expression that doesn't exist, which would otherwise quietly be
+// empty. Arguments must be declared by the step, which has the shared
+// arguments of the set it uses.
+func checkWhenNames(cmd repo.Command, names whenNames) error {
+	tokens, err := tokenizeWhen(cmd.When)
+	if err != nil {
+		return fmt.Errorf("invalid when expression '%s': %w", cmd.When, err)
+	}
+	args := make(map[string]bool)
+	for _, argDef := range cmd.Args {
+		args[argDef.Name] = true
+	}
+	for _, t := range tokens {
+		if t.kind != whenIdent {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(t.value, "args."):
+			if !args[strings.TrimPrefix(t.value, "args.")] {
+				return fmt.Errorf("invalid when expression '%s': unknown name '%s', the argument is not declared by the set or the step", cmd.When, t.value)
+			}
+		case strings.HasPrefix(t.value, "vars."):
+			if !names.vars[strings.TrimPrefix(t.value, "vars.")] {
+				return fmt.Errorf("invalid when expression '%s': unknown name '%s', no step registers it", cmd.When, t.value)
+			}
+		case strings.HasPrefix(t.value, "steps."):
+			parts := strings.Split(t.value, ".")
+			step, err := strconv.Atoi(parts[1])
+			if len(parts) != 3 || err != nil || step < 1 || step > names.steps || (parts[2] != "status" && parts[2] != "exit_code") {
+				return fmt.Errorf("invalid when expression '%s': unknown name '%s', use steps.<n>.status or steps.<n>.exit_code with n from 1 to %d", cmd.When, t.value, names.steps)
+			}
+		}
+	}
+	return nil
+}
+
+// tokenizeWhen splits a when expression into tokens
+func tokenizeWhen(expr string) ([]whenToken, error) {
+	var tokens []whenToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string starting at position %d", i+1)
+			}
+			tokens = append(tokens, whenToken{whenString, expr[i+1 : i+1+end]})
+			i += end + 2
+		case strings.HasPrefix(expr[i:], "&&"), strings.HasPrefix(expr[i:], "||"),
+			strings.HasPrefix(expr[i:], "=="), strings.HasPrefix(expr[i:], "!="):
+			tokens = append(tokens, whenToken{whenOp, expr[i : i+2]})
+			i += 2
+		case c == '!' || c == '(' || c == ')':
+			tokens = append(tokens, whenToken{whenOp, string(c)})
+			i++
+		case isWhenIdentChar(c):
+			start := i
+			for i < len(expr) && isWhenIdentChar(expr[i]) {
+				i++
+			}
+			tokens = append(tokens, whenToken{whenIdent, expr[start:i]})
+		default:
+			return nil, fmt.Errorf("unexpected character '%c' at position %d", c, i+1)
+		}
+	}
+	return append(tokens, whenToken{kind: whenEOF}), nil
+}
+
+func isWhenIdentChar(c byte) bool {
+	return c == '_' || c == '.' || c == '-' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// whenParser evaluates a tokenized when expression against a set of facts
+type whenParser struct {
+	tokens []whenToken
+	pos    int
+	facts  map[string]string
+}
+
+// evaluateWhen evaluates a when expression. An empty expression is always true.
+func evaluateWhen(expr string, facts map[string]string) (bool, error) {
+	if strings.TrimSpace(expr) == "" {
+		return true, nil
+	}
+
+	tokens, err := tokenizeWhen(expr)
+	if err != nil {
+		return false, fmt.Errorf("invalid when expression '%s': %w", expr, err)
+	}
+
+	p := &whenParser{tokens: tokens, facts: facts}
+	result, err := p.parseOr()
+	if err == nil && p.peek().kind != whenEOF {
+		err = fmt.Errorf("unexpected '%s'", p.peek().value)
+	}
+	if err != nil {
+		return false, fmt.Errorf("invalid when expression '%s': %w", expr, err)
+	}
+	return isTruthy(result), nil
+}
+
+func (p *whenParser) peek() whenToken {
+	return p.tokens[p.pos]
+}
+
+func (p *whenParser) next() whenToken {
+	t := p.tokens[p.pos]
+	if t.kind != whenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *whenParser) acceptOp(op string) bool {
+	if t := p.peek(); t.kind == whenOp && t.value == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// parseOr parses: and ('||' and)*
+func (p *whenParser) parseOr() (string, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return "", err
+	}
+	for p.acceptOp("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return "", err
+		}
+		left = boolString(isTruthy(left) || isTruthy(right))
+	}
+	return left, nil
+}
+
+// parseAnd parses: unary ('&&' unary)*
+func (p *whenParser) parseAnd() (string, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return "", err
+	}
+	for p.acceptOp("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return "", err
+		}
+		left = boolString(isTruthy(left) && isTruthy(right))
+	}
+	return left, nil
+}
+
+// parseUnary parses: '!' unary | comparison
+func (p *whenParser) parseUnary() (string, error) {
+	if p.acceptOp("!") {
+		value, err := p.parseUnary()
+		if err != nil {
+			return "", err
+		}
+		return boolString(!isTruthy(value)), nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison parses: operand (('==' | '!=') operand)?
+func (p *whenParser) parseComparison() (string, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return "", err
+	}
+	if p.acceptOp("==") {
+		right, err := p.parseOperand()
+		if err != nil {
+			return "", err
+		}
+		return boolString(left == right), nil
+	}
+	if p.acceptOp("!=") {
+		right, err := p.parseOperand()
+		if err != nil {
+			return "", err
+		}
+		return boolString(left != right), nil
+	}
+	return left, nil
+}
+
+// parseOperand parses: '(' or ')' | string | identifier
+func (p *whenParser) parseOperand() (string, error) {
+	t := p.next()
+	switch t.kind {
+	case whenString:
+		return t.value, nil
+	case whenIdent:
+		return p.lookup(t.value)
+	case whenOp:
+		if t.value == "(" {
+			value, err := p.parseOr()
+			if err != nil {
+				return "", err
+			}
+			if !p.acceptOp(")") {
+				return "", fmt.Errorf("missing ')'")
+			}
+			return value, nil
+		}
+		return "", fmt.Errorf("unexpected '%s'", t.value)
+	default:
+		return "", fmt.Errorf("unexpected end of expression")
+	}
+}
+
+// lookup resolves an identifier to a fact, a literal or an error for unknown names
+func (p *whenParser) lookup(name string) (string, error) {
+	if value, exists := p.facts[name]; exists {
+		return value, nil
+	}
+	switch name {
+	case "true", "false":
+		return name, nil
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		return name, nil // Numbers compare as strings
+	}
+	for _, ns := range whenNamespaces {
+		if strings.HasPrefix(name, ns) {
+			return "", nil
+		}
+	}
+	return "", fmt.Errorf("unknown name '%s'", name)
+}
+
+// isTruthy reports whether a value counts as true in a when expression
+func isTruthy(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "false", "no", "off", "0":
+		return false
+	}
+	return true
+}
+
+func boolString(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/shelldock/shelldock/internal/repo"
+)
+
+func TestEvaluateWhen(t *testing.T) {
+	facts := map[string]string{
+		"platform":          "ubuntu",
+		"arch":              "arm64",
+		"args.enable_ssl":   "yes",
+		"args.domain":       "example.com",
+		"args.debug":        "false",
+		"vars.latest":       "v1.2.3",
+		"steps.2.status":    "success",
+		"steps.2.exit_code": "0",
+	}
+
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{"", true},
+		{"arch == 'arm64'", true},
+		{"arch != \"arm64\"", false},
+		{"args.enable_ssl == 'yes'", true},
+		{"args.enable_ssl == 'yes' && platform == 'centos'", false},
+		{"args.enable_ssl == 'no' || platform == 'ubuntu'", true},
+		{"!(platform == 'ubuntu')", false},
+		{"args.domain", true},
+		{"args.debug", false},
+		{"!args.debug", true},
+		{"args.undefined", false},
+		{"steps.2.status == 'success' && steps.2.exit_code == 0", true},
+		{"steps.3.status == 'success'", false},
+		{"true && !false", true},
+		{"vars.latest == 'v1.2.3'", true},
+		{"vars.unset", false},
+	}
+
+	for _, tt := range tests {
+		result, err := evaluateWhen(tt.expr, facts)
+		if err != nil {
+			t.Errorf("evaluateWhen(%q) unexpected error: %v", tt.expr, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("evaluateWhen(%q) = %v, expected %v", tt.expr, result, tt.expected)
+		}
+	}
+}
+
+func TestEvaluateWhen_Invalid(t *testing.T) {
+	invalid := []string{
+		"arch ==",
+		"arch = 'arm64'",
+		"(arch == 'arm64'",
+		"archh == 'arm64'", // Unknown name
+		"arch == 'arm64",   // Unterminated string
+		"arch == 'arm64' 'x'",
+	}
+
+	for _, expr := range invalid {
+		if _, err := evaluateWhen(expr, map[string]string{"arch": "amd64"}); err == nil {
+			t.Errorf("evaluateWhen(%q) expected error, got nil", expr)
+		}
+	}
+}
+
+func TestCheckWhenNames(t *testing.T) {
+	names := newWhenNames(&repo.CommandSet{
+		Commands: []repo.Command{{Register: "latest"}, {}},
+	})
+	args := []repo.ArgumentDef{{Name: "domain"}, {Name: "enable_ssl"}}
+
+	tests := []struct {
+		expr  string
+		valid bool
+	}{
+		{"", true},
+		{"args.enable_ssl == 'yes' && args.domain != ''", true},
+		{"vars.latest == 'v1' || steps.1.status == 'success' && steps.2.exit_code == 0", true},
+		{"arch == 'arm64' && platform != 'darwin'", true},
+		{"args.enable_tls == 'true'", false},
+		{"vars.version", false},
+		{"steps.3.status == 'success'", false},
+		{"steps.1.state == 'success'", false},
+		{"steps.first.status", false},
+		{"steps.1", false},
+	}
+
+	for _, tt := range tests {
+		err := checkWhenNames(repo.Command{When: tt.expr, Args: args}, names)
+		if tt.valid && err != nil {
+			t.Errorf("checkWhenNames(%q) unexpected error: %v", tt.expr, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("checkWhenNames(%q) expected error, got nil", tt.expr)
+		}
+	}
+}
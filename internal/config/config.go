@@ -13,20 +13,32 @@ import (
 const (
 	ConfigFileName = ".sdrc"
 	DefaultPlatform = "auto" // auto-detect
+	DefaultShell    = "sh"   // shell for sets that don't name one
 )
 
 // Config represents the ShellDock configuration
 type Config struct {
-	Platform string `yaml:"platform"` // linux, darwin, windows, or "auto"
+	Platform     string   `yaml:"platform"`                // linux, darwin, windows, or "auto"
+	Shell        string   `yaml:"shell,omitempty"`         // Shell running commands of sets that don't name one (default: sh)
+	ShellOptions []string `yaml:"shell_options,omitempty"` // Options the default shell is started with (e.g., pipefail)
 }
 
-// GetConfigPath returns the path to the config file
-func GetConfigPath() (string, error) {
+// GetShellDockDir returns the path to the ShellDock data directory (~/.shelldock)
+func GetShellDockDir() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to get home directory: %w", err)
 	}
-	return filepath.Join(homeDir, ".shelldock", ConfigFileName), nil
+	return filepath.Join(homeDir, ".shelldock"), nil
+}
+
+// GetConfigPath returns the path to the config file
+func GetConfigPath() (string, error) {
+	dir, err := GetShellDockDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ConfigFileName), nil
 }
 
 // LoadConfig loads the configuration from ~/.shelldock/.sdrc
@@ -97,6 +109,21 @@ func GetPlatform() (string, error) {
 	return config.Platform, nil
 }
 
+// GetShell returns the shell that runs the commands of sets that don't name
+// one, and the options it is started with
+func GetShell() (string, []string, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return DefaultShell, nil, err
+	}
+
+	if config.Shell == "" {
+		return DefaultShell, config.ShellOptions, nil
+	}
+
+	return config.Shell, config.ShellOptions, nil
+}
+
 // DetectPlatform detects the current platform
 // For Linux, it also detects the distribution (ubuntu, debian, centos, fedora, arch, etc.)
 func DetectPlatform() string {
@@ -113,6 +140,19 @@ func DetectPlatform() string {
 	}
 }
 
+// DetectArch returns the CPU architecture (amd64, arm64, ...)
+func DetectArch() string {
+	return runtime.GOARCH
+}
+
+// DetectDistribution returns the Linux distribution, or the OS name on other platforms
+func DetectDistribution() string {
+	if runtime.GOOS == "linux" {
+		return DetectLinuxDistribution()
+	}
+	return runtime.GOOS
+}
+
 // DetectLinuxDistribution detects the Linux distribution
 func DetectLinuxDistribution() string {
 	// Try to read /etc/os-release (most common)
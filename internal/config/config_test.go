@@ -162,6 +162,39 @@ func TestGetPlatform_Auto(t *testing.T) {
 	}
 }
 
+func TestGetShell(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Temporarily override HOME
+	originalHome := os.Getenv("HOME")
+	defer func() {
+		if originalHome != "" {
+			_ = os.Setenv("HOME", originalHome)
+		}
+	}()
+
+	_ = os.Setenv("HOME", tmpDir)
+
+	shell, options, err := GetShell()
+	if err != nil {
+		t.Fatalf("GetShell failed: %v", err)
+	}
+	if shell != DefaultShell || len(options) != 0 {
+		t.Errorf("Expected shell '%s' without options, got '%s' %v", DefaultShell, shell, options)
+	}
+
+	if err := SaveConfig(&Config{Platform: "auto", Shell: "bash", ShellOptions: []string{"pipefail"}}); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	shell, options, err = GetShell()
+	if err != nil {
+		t.Fatalf("GetShell failed: %v", err)
+	}
+	if shell != "bash" || len(options) != 1 || options[0] != "pipefail" {
+		t.Errorf("Expected shell 'bash' with [pipefail], got '%s' %v", shell, options)
+	}
+}
+
 func TestDetectPlatform(t *testing.T) {
 	platform := DetectPlatform()
 	if platform == "" {
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// DetectFacts returns facts about the host that command templates can use:
+// distro_version, arch, hostname, user, home, nproc and mem_total (in bytes).
+// Facts that cannot be detected are empty.
+func DetectFacts() map[string]string {
+	hostname, _ := os.Hostname()
+	home, _ := os.UserHomeDir()
+
+	return map[string]string{
+		"distro_version": DetectDistroVersion(),
+		"arch":           DetectArch(),
+		"hostname":       hostname,
+		"user":           DetectUser(),
+		"home":           home,
+		"nproc":          strconv.Itoa(runtime.NumCPU()),
+		"mem_total":      DetectMemTotal(),
+	}
+}
+
+// DetectUser returns the name of the user running ShellDock
+func DetectUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
+
+// DetectDistroVersion returns the version of the Linux distribution (VERSION_ID
+// in /etc/os-release) or of macOS
+func DetectDistroVersion() string {
+	switch runtime.GOOS {
+	case "linux":
+		data, err := os.ReadFile("/etc/os-release")
+		if err != nil {
+			return ""
+		}
+		return parseOSReleaseField(string(data), "VERSION_ID")
+	case "darwin":
+		out, err := exec.Command("sw_vers", "-productVersion").Output()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+	return ""
+}
+
+// DetectMemTotal returns the total memory of the host in bytes
+func DetectMemTotal() string {
+	switch runtime.GOOS {
+	case "linux":
+		data, err := os.ReadFile("/proc/meminfo")
+		if err != nil {
+			return ""
+		}
+		return parseMemTotal(string(data))
+	case "darwin":
+		out, err := exec.Command("sysctl", "-n", "hw.memsize").Output()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+	return ""
+}
+
+// parseOSReleaseField returns the value of a field in os-release content
+func parseOSReleaseField(data, field string) string {
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, field+"=") {
+			return strings.Trim(strings.TrimPrefix(line, field+"="), "\"'")
+		}
+	}
+	return ""
+}
+
+// parseMemTotal returns the MemTotal line of /proc/meminfo in bytes
+func parseMemTotal(data string) string {
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return ""
+		}
+		return strconv.FormatUint(kb*1024, 10)
+	}
+	return ""
+}
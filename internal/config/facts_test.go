@@ -0,0 +1,56 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestDetectFacts(t *testing.T) {
+	facts := DetectFacts()
+
+	for _, name := range []string{"distro_version", "arch", "hostname", "user", "home", "nproc", "mem_total"} {
+		if _, exists := facts[name]; !exists {
+			t.Errorf("Expected fact %q", name)
+		}
+	}
+	if facts["arch"] == "" || facts["nproc"] == "" {
+		t.Errorf("Expected arch and nproc to be detected, got %+v", facts)
+	}
+}
+
+func TestParseOSReleaseField(t *testing.T) {
+	data := "NAME=\"Ubuntu\"\nVERSION_ID=\"22.04\"\nID=ubuntu\n"
+
+	tests := []struct {
+		field    string
+		expected string
+	}{
+		{"VERSION_ID", "22.04"},
+		{"ID", "ubuntu"},
+		{"VERSION", ""},
+	}
+
+	for _, tt := range tests {
+		result := parseOSReleaseField(data, tt.field)
+		if result != tt.expected {
+			t.Errorf("parseOSReleaseField(%q) = %q, expected %q", tt.field, result, tt.expected)
+		}
+	}
+}
+
+func TestParseMemTotal(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"MemTotal:        2048 kB\nMemFree:  1024 kB\n", "2097152"},
+		{"MemFree:  1024 kB\n", ""},
+		{"MemTotal: lots kB\n", ""},
+	}
+
+	for _, tt := range tests {
+		result := parseMemTotal(tt.input)
+		if result != tt.expected {
+			t.Errorf("parseMemTotal(%q) = %q, expected %q", tt.input, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,483 @@
+package render
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Placeholder is a value that is not known yet, such as the output of a step
+// that has not run. It renders as {{name}} and is never quoted.
+type Placeholder string
+
+// String returns the placeholder as written in the template
+func (p Placeholder) String() string {
+	return "{{" + string(p) + "}}"
+}
+
+// Secret is the value of a secret argument, held in the named environment
+// variable. It renders as a double-quoted reference to the variable, so the
+// value never appears in the command line, and is never quoted again. Inside
+// quotes in the template, the reference is written so the shell still expands
+// it as one word.
+type Secret string
+
+// String returns the reference to the environment variable
+func (s Secret) String() string {
+	return `"${` + string(s) + `}"`
+}
+
+// inQuotes returns the reference to the environment variable for use inside
+// a quoted string: as it is in double quotes, and with the single-quoted
+// string closed around it in single quotes, where the shell expands nothing
+func (s Secret) inQuotes(quote byte) string {
+	if quote == '"' {
+		return "${" + string(s) + "}"
+	}
+	return "'" + s.String() + "'"
+}
+
+// Redacted stands for the value of a secret argument in commands that are
+// shown rather than run. It renders as ****.
+type Redacted string
+
+// String returns the mask shown instead of the value
+func (Redacted) String() string {
+	return "****"
+}
+
+// List is the value of a list argument. It renders as its items separated by
+// spaces, and can be looped over with range.
+type List []string
+
+// String returns the items separated by spaces
+func (l List) String() string {
+	return strings.Join(l, " ")
+}
+
+// autoQuote is the function appended to actions when values are quoted by default
+const autoQuote = "_quote"
+
+// Functions appended to the actions inside single or double quotes, which
+// write secrets as references the shell expands there
+const (
+	inSingleQuotes = "_in_single_quotes"
+	inDoubleQuotes = "_in_double_quotes"
+)
+
+// namePattern matches names that can be used as placeholders
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// sizePattern matches sizes like 2G, 512M, 1.5GiB or a number of bytes
+var sizePattern = regexp.MustCompile(`(?i)^([0-9]+(?:\.[0-9]+)?)\s*([KMGT]?)(?:I?B)?$`)
+
+// sizeUnits are the multipliers of size units, in bytes
+var sizeUnits = map[string]float64{"": 1, "K": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40}
+
+// builtins are the functions every template has
+var builtins = map[string]bool{
+	"and": true, "call": true, "html": true, "index": true, "slice": true, "js": true, "len": true,
+	"not": true, "or": true, "print": true, "printf": true, "println": true, "urlquery": true,
+	"eq": true, "ge": true, "gt": true, "le": true, "lt": true, "ne": true,
+}
+
+// filters are the functions available in templates
+var filters = template.FuncMap{
+	"upper":   stringFilter(strings.ToUpper),
+	"lower":   stringFilter(strings.ToLower),
+	"quote":   quote,
+	"raw":     func(v any) any { return v },
+	"b64enc":  stringFilter(func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }),
+	"mib":     mib,
+	"default": defaultValue,
+	"split":   func(sep string, v any) List { return splitList(fmt.Sprint(v), sep) },
+	"join":    func(sep string, v any) string { return join(sep, v) },
+	autoQuote: quote,
+
+	inSingleQuotes: func(v any) any { return secretIn('\'', v) },
+	inDoubleQuotes: func(v any) any { return secretIn('"', v) },
+}
+
+// IsFilter reports whether name is the name of a filter, which can't be used
+// as the name of a value
+func IsFilter(name string) bool {
+	_, exists := filters[name]
+	return exists
+}
+
+// Render renders a command template. Values are referenced by name, e.g.
+// {{name}} or {{name | upper}}; a name without a value is an error. Go
+// templates meant for the command itself, like docker's --format
+// '{{.Names}}', are left as they are. With quoteValues, the output of every
+// action is shell-quoted unless it ends with the raw or quote filter.
+func Render(text string, values map[string]any, quoteValues bool) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	funcs := template.FuncMap{}
+	for name, fn := range filters {
+		funcs[name] = fn
+	}
+	for name, value := range values {
+		if IsFilter(name) {
+			return "", fmt.Errorf("'%s' is the name of a filter and can't be used as a value name", name)
+		}
+		if namePattern.MatchString(name) {
+			value := value
+			funcs[name] = func() any { return value }
+		}
+	}
+
+	// Functions are checked once the command's own templates are set aside,
+	// which may call functions like docker's json
+	tree := parse.New("command")
+	tree.Mode = parse.SkipFuncCheck
+	trees := map[string]*parse.Tree{}
+	if _, err := tree.Parse(text, "", "", trees, funcs); err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+	tmpl := template.New("command").Funcs(funcs)
+	for name, t := range trees {
+		passThrough(t.Root)
+		if name, bare := undefinedFunc(t.Root, funcs); name != "" {
+			if bare {
+				return "", fmt.Errorf("unresolved placeholder {{%s}}", name)
+			}
+			return "", fmt.Errorf("invalid template: function %q not defined", name)
+		}
+		if _, err := tmpl.AddParseTree(name, t); err != nil {
+			return "", fmt.Errorf("invalid template: %w", err)
+		}
+	}
+	if quoteValues {
+		addQuoting(tmpl.Tree, tmpl.Tree.Root)
+	}
+	addQuoteContext(tmpl.Tree, tmpl.Tree.Root, 0)
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, nil); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return out.String(), nil
+}
+
+// ShellQuote quotes a string for use as a single POSIX shell word
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// passThrough replaces the actions and blocks that use the template's data,
+// which placeholders never do, with their text. Inside range and with, the
+// data is the current item, so their bodies are left alone.
+func passThrough(list *parse.ListNode) {
+	if list == nil {
+		return
+	}
+	for i, node := range list.Nodes {
+		var pipe *parse.PipeNode
+		var branches []*parse.ListNode
+		switch n := node.(type) {
+		case *parse.ActionNode:
+			pipe = n.Pipe
+		case *parse.TemplateNode:
+			pipe = n.Pipe
+		case *parse.IfNode:
+			pipe, branches = n.Pipe, []*parse.ListNode{n.List, n.ElseList}
+		case *parse.RangeNode:
+			pipe, branches = n.Pipe, []*parse.ListNode{n.ElseList}
+		case *parse.WithNode:
+			pipe, branches = n.Pipe, []*parse.ListNode{n.ElseList}
+		default:
+			continue
+		}
+		if usesData(pipe) {
+			list.Nodes[i] = &parse.TextNode{NodeType: parse.NodeText, Pos: node.Position(), Text: []byte(node.String())}
+			continue
+		}
+		for _, branch := range branches {
+			passThrough(branch)
+		}
+	}
+}
+
+// usesData reports whether a pipeline refers to the template's data with dot,
+// a field like .Names or a variable
+func usesData(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		if len(n.Decl) > 0 {
+			return true
+		}
+		for _, cmd := range n.Cmds {
+			if usesData(cmd) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if usesData(arg) {
+				return true
+			}
+		}
+	case *parse.ChainNode:
+		return usesData(n.Node)
+	case *parse.DotNode, *parse.FieldNode, *parse.VariableNode:
+		return true
+	}
+	return false
+}
+
+// undefinedFunc returns the first function called under node that is neither
+// in funcs nor built in, and whether it is called without arguments, as in
+// {{name}} or {{name | upper}}, which is how placeholders are written
+func undefinedFunc(node parse.Node, funcs template.FuncMap) (string, bool) {
+	var nodes []parse.Node
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return "", false
+		}
+		nodes = n.Nodes
+	case *parse.ActionNode:
+		nodes = []parse.Node{n.Pipe}
+	case *parse.TemplateNode:
+		nodes = []parse.Node{n.Pipe}
+	case *parse.IfNode:
+		nodes = []parse.Node{n.Pipe, n.List, n.ElseList}
+	case *parse.RangeNode:
+		nodes = []parse.Node{n.Pipe, n.List, n.ElseList}
+	case *parse.WithNode:
+		nodes = []parse.Node{n.Pipe, n.List, n.ElseList}
+	case *parse.PipeNode:
+		if n == nil {
+			return "", false
+		}
+		for _, cmd := range n.Cmds {
+			nodes = append(nodes, cmd)
+		}
+	case *parse.ChainNode:
+		nodes = []parse.Node{n.Node}
+	case *parse.CommandNode:
+		if name, _ := undefinedFunc(n.Args[0], funcs); name != "" {
+			return name, len(n.Args) == 1
+		}
+		nodes = n.Args[1:]
+	case *parse.IdentifierNode:
+		if _, exists := funcs[n.Ident]; !exists && !builtins[n.Ident] {
+			return n.Ident, true
+		}
+	}
+	for _, child := range nodes {
+		if name, bare := undefinedFunc(child, funcs); name != "" {
+			return name, bare
+		}
+	}
+	return "", false
+}
+
+// addQuoting appends the quoting function to every action that prints a value
+func addQuoting(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			addQuoting(tree, child)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 || len(n.Pipe.Cmds) == 0 {
+			return
+		}
+		last := n.Pipe.Cmds[len(n.Pipe.Cmds)-1]
+		if ident, ok := last.Args[0].(*parse.IdentifierNode); ok && (ident.Ident == "raw" || ident.Ident == "quote") {
+			return
+		}
+		fn := parse.NewIdentifier(autoQuote).SetTree(tree).SetPos(n.Pos)
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pos, Args: []parse.Node{fn}})
+	case *parse.IfNode:
+		addQuoting(tree, n.List)
+		addQuoting(tree, n.ElseList)
+	case *parse.RangeNode:
+		addQuoting(tree, n.List)
+		addQuoting(tree, n.ElseList)
+	case *parse.WithNode:
+		addQuoting(tree, n.List)
+		addQuoting(tree, n.ElseList)
+	}
+}
+
+// addQuoteContext appends the function for the quotes an action is in, if any,
+// to every action that prints a value. quote is the quote character open
+// before the node, or 0, and the one open after it is returned. Values are
+// taken not to open or close quotes, and branches to leave them as they were.
+func addQuoteContext(tree *parse.Tree, node parse.Node, quote byte) byte {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return quote
+		}
+		for _, child := range n.Nodes {
+			quote = addQuoteContext(tree, child, quote)
+		}
+	case *parse.TextNode:
+		quote = scanQuotes(n.Text, quote, n.Pos == 0)
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 || len(n.Pipe.Cmds) == 0 || quote == 0 {
+			return quote
+		}
+		name := inDoubleQuotes
+		if quote == '\'' {
+			name = inSingleQuotes
+		}
+		fn := parse.NewIdentifier(name).SetTree(tree).SetPos(n.Pos)
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pos, Args: []parse.Node{fn}})
+	case *parse.IfNode:
+		addQuoteContext(tree, n.ElseList, quote)
+		return addQuoteContext(tree, n.List, quote)
+	case *parse.RangeNode:
+		addQuoteContext(tree, n.ElseList, quote)
+		return addQuoteContext(tree, n.List, quote)
+	case *parse.WithNode:
+		addQuoteContext(tree, n.ElseList, quote)
+		return addQuoteContext(tree, n.List, quote)
+	}
+	return quote
+}
+
+// scanQuotes returns the quote character open after a piece of shell text,
+// given the one open before it, or 0. Comments are skipped; start tells whether
+// the text starts a word, as at the beginning of the template.
+func scanQuotes(text []byte, quote byte, start bool) byte {
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case c == '\\':
+			i++ // The next character is escaped
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '#' && (i == 0 && start || i > 0 && strings.IndexByte(" \t\n;&|(", text[i-1]) >= 0):
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		}
+	}
+	return quote
+}
+
+// secretIn writes a secret as a reference for use inside the given quotes.
+// Other values are left as they are.
+func secretIn(quote byte, v any) any {
+	if secret, ok := v.(Secret); ok {
+		return secret.inQuotes(quote)
+	}
+	return v
+}
+
+// stringFilter returns a filter applying fn to the value as a string.
+// Placeholders pass through unchanged, and secrets can't be changed since
+// their values are not part of the command.
+func stringFilter(fn func(string) string) func(v any) (any, error) {
+	return func(v any) (any, error) {
+		switch value := v.(type) {
+		case Placeholder:
+			return value, nil
+		case Secret, Redacted:
+			return nil, fmt.Errorf("filters can't change secret values")
+		}
+		return fn(fmt.Sprint(v)), nil
+	}
+}
+
+// mib converts a size such as 2G, 512M or a number of bytes to whole mebibytes,
+// e.g. for dd's count with bs=1M. Placeholders pass through unchanged.
+func mib(v any) (any, error) {
+	switch value := v.(type) {
+	case Placeholder:
+		return value, nil
+	case Secret, Redacted:
+		return nil, fmt.Errorf("filters can't change secret values")
+	}
+	size := strings.TrimSpace(fmt.Sprint(v))
+	match := sizePattern.FindStringSubmatch(size)
+	if match == nil {
+		return nil, fmt.Errorf("mib: %q is not a size", size)
+	}
+	n, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("mib: %q is not a size", size)
+	}
+	return strconv.FormatInt(int64(n*sizeUnits[strings.ToUpper(match[2])]/(1<<20)), 10), nil
+}
+
+// quote shell-quotes a value. The items of a list are quoted separately, and
+// placeholders and secrets are left as they are.
+func quote(v any) any {
+	switch value := v.(type) {
+	case Placeholder, Secret, Redacted:
+		return value
+	case List:
+		quoted := make([]string, len(value))
+		for i, item := range value {
+			quoted[i] = ShellQuote(item)
+		}
+		return strings.Join(quoted, " ")
+	}
+	return ShellQuote(fmt.Sprint(v))
+}
+
+// defaultValue returns the value, or def if the value is empty
+func defaultValue(def string, v any) any {
+	switch value := v.(type) {
+	case string:
+		if value == "" {
+			return def
+		}
+	case List:
+		if len(value) == 0 {
+			return def
+		}
+	case nil:
+		return def
+	}
+	return v
+}
+
+// join joins the items of a list with sep
+func join(sep string, v any) string {
+	if list, ok := v.(List); ok {
+		return strings.Join(list, sep)
+	}
+	return fmt.Sprint(v)
+}
+
+// splitList splits a value on sep, trimming the items and dropping empty ones
+func splitList(value, sep string) List {
+	var items List
+	for _, item := range strings.Split(value, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// SplitList splits the value of a list argument on commas
+func SplitList(value string) List {
+	return splitList(value, ",")
+}
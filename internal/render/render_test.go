@@ -0,0 +1,153 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	values := map[string]any{
+		"name":     "John Doe",
+		"mode":     "prod",
+		"empty":    "",
+		"packages": List{"yarn", "pnpm"},
+		"output":   Placeholder("output"),
+		"password": Secret("SHELLDOCK_SECRET_PASSWORD"),
+		"token":    Redacted("token"),
+		"size":     "2G",
+		"half":     "512M",
+		"mem":      "6294937600",
+		"big":      "1.5GiB",
+	}
+
+	tests := []struct {
+		text     string
+		quote    bool
+		expected string
+	}{
+		{"echo hello", true, "echo hello"},
+		{"echo {{name}}", false, "echo John Doe"},
+		{"echo {{name}}", true, "echo 'John Doe'"},
+		{"echo {{ name }}", true, "echo 'John Doe'"},
+		{"echo {{name|raw}}", true, "echo John Doe"},
+		{"echo {{name|quote}}", false, "echo 'John Doe'"},
+		{"echo {{name | upper}}", false, "echo JOHN DOE"},
+		{"echo {{name | lower}}", false, "echo john doe"},
+		{"echo {{name | b64enc}}", false, "echo Sm9obiBEb2U="},
+		{`echo {{empty | default "none"}}`, false, "echo none"},
+		{`echo {{name | default "none"}}`, false, "echo John Doe"},
+		{"echo {{empty}}", true, "echo ''"},
+		{`build{{if eq mode "prod"}} --release{{end}}`, true, "build --release"},
+		{`build{{if eq mode "dev"}} --debug{{else}} --release{{end}}`, true, "build --release"},
+		{"{{range packages}}npm i {{.}}; {{end}}", true, "npm i 'yarn'; npm i 'pnpm'; "},
+		{"npm i {{packages}}", false, "npm i yarn pnpm"},
+		{"npm i {{packages}}", true, "npm i 'yarn' 'pnpm'"},
+		{`echo {{packages | join ","}}`, false, "echo yarn,pnpm"},
+		{`{{range split "," "a, b"}}[{{.}}]{{end}}`, false, "[a][b]"},
+		{"echo {{output}}", true, "echo {{output}}"},
+		{"echo {{output | upper}}", true, "echo {{output}}"},
+		{"login -p {{password}}", true, `login -p "${SHELLDOCK_SECRET_PASSWORD}"`},
+		{"login -p {{password}}", false, `login -p "${SHELLDOCK_SECRET_PASSWORD}"`},
+		{"login -p {{password|quote}}", false, `login -p "${SHELLDOCK_SECRET_PASSWORD}"`},
+		{"login -p {{token}}", true, "login -p ****"},
+		{`login -p "{{password}}"`, false, `login -p "${SHELLDOCK_SECRET_PASSWORD}"`},
+		{`login -p "{{password}}"`, true, `login -p "${SHELLDOCK_SECRET_PASSWORD}"`},
+		{`login -p '{{password}}'`, false, `login -p ''"${SHELLDOCK_SECRET_PASSWORD}"''`},
+		{`login -p '{{password|quote}}'`, false, `login -p ''"${SHELLDOCK_SECRET_PASSWORD}"''`},
+		{`echo "it's" \" '{{password}}'`, false, `echo "it's" \" ''"${SHELLDOCK_SECRET_PASSWORD}"''`},
+		{"# don't\nlogin -p {{password}}", false, "# don't\nlogin -p \"${SHELLDOCK_SECRET_PASSWORD}\""},
+		{`{{if eq mode "prod"}}login "{{password}}"{{end}} {{password}}`, false, `login "${SHELLDOCK_SECRET_PASSWORD}" "${SHELLDOCK_SECRET_PASSWORD}"`},
+		{`login -p "{{token}}"`, true, `login -p "****"`},
+		{"dd count={{size | mib}}", false, "dd count=2048"},
+		{"dd count={{half | mib}}", false, "dd count=512"},
+		{"dd count={{mem | mib}}", false, "dd count=6003"},
+		{"dd count={{big | mib}}", false, "dd count=1536"},
+		{"dd count={{output | mib}}", false, "dd count={{output}}"},
+		{"docker ps --format '{{.Names}}'", true, "docker ps --format '{{.Names}}'"},
+		{"docker ps --format '{{.Names}} {{name}}'", false, "docker ps --format '{{.Names}} John Doe'"},
+		{"kubectl get pods -o go-template='{{range .items}}{{.metadata.name}}{{end}}'", true, "kubectl get pods -o go-template='{{range .items}}{{.metadata.name}}{{end}}'"},
+		{`helm install --set 'x={{ .Values.tag | default "v1" }}'`, true, `helm install --set 'x={{.Values.tag | default "v1"}}'`},
+		{`{{if eq mode "prod"}}echo {{.Env}}{{end}}`, false, "echo {{.Env}}"},
+		{"docker inspect --format '{{json .}}' web", true, "docker inspect --format '{{json .}}' web"},
+		{"docker inspect --format '{{json .State}}' {{name}}", false, "docker inspect --format '{{json .State}}' John Doe"},
+		{"docker ps --format 'table {{.Names}}\t{{.Status | printf \"%.10s\"}}'", true, "docker ps --format 'table {{.Names}}\t{{.Status | printf \"%.10s\"}}'"},
+	}
+
+	for _, tt := range tests {
+		result, err := Render(tt.text, values, tt.quote)
+		if err != nil {
+			t.Errorf("Render(%q, quote=%v) unexpected error: %v", tt.text, tt.quote, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("Render(%q, quote=%v) = %q, expected %q", tt.text, tt.quote, result, tt.expected)
+		}
+	}
+}
+
+func TestRender_Errors(t *testing.T) {
+	tests := []struct {
+		text     string
+		contains string
+	}{
+		{"echo {{missing}}", "unresolved placeholder {{missing}}"},
+		{"echo {{name | nosuchfilter}}", "unresolved placeholder {{nosuchfilter}}"},
+		{`echo {{printf "%s" missing}}`, "unresolved placeholder {{missing}}"},
+		{`echo {{json "x"}}`, `function "json" not defined`},
+		{"echo {{if}}", "invalid template"},
+		{"echo {{name", "invalid template"},
+		{"echo {{password | upper}}", "secret"},
+		{"echo {{name | mib}}", "not a size"},
+	}
+
+	for _, tt := range tests {
+		_, err := Render(tt.text, map[string]any{"name": "x", "password": Secret("PASSWORD")}, false)
+		if err == nil || !strings.Contains(err.Error(), tt.contains) {
+			t.Errorf("Render(%q) expected error containing %q, got %v", tt.text, tt.contains, err)
+		}
+	}
+}
+
+func TestRender_FilterNames(t *testing.T) {
+	// A value named like a filter would replace the filter in every template
+	for _, name := range []string{"default", "upper", "quote", "join"} {
+		_, err := Render("echo {{name}}", map[string]any{"name": "x", name: "y"}, false)
+		if err == nil || !strings.Contains(err.Error(), "name of a filter") {
+			t.Errorf("Render() with a value named %q expected error, got %v", name, err)
+		}
+		if !IsFilter(name) {
+			t.Errorf("IsFilter(%q) = false, want true", name)
+		}
+	}
+	if IsFilter("name") {
+		t.Error("IsFilter(\"name\") = true, want false")
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"plain", "'plain'"},
+		{"", "''"},
+		{"a b", "'a b'"},
+		{"it's", `'it'\''s'`},
+		{"x; rm -rf /", "'x; rm -rf /'"},
+	}
+
+	for _, tt := range tests {
+		if result := ShellQuote(tt.input); result != tt.expected {
+			t.Errorf("ShellQuote(%q) = %q, expected %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	if items := SplitList(" a, b ,,c "); len(items) != 3 || items[0] != "a" || items[2] != "c" {
+		t.Errorf("Unexpected items: %q", items)
+	}
+	if items := SplitList(""); len(items) != 0 {
+		t.Errorf("Expected no items, got %q", items)
+	}
+}
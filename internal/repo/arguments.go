@@ -0,0 +1,217 @@
+package repo
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MergeArgumentDefs returns the definitions of base, with definitions of the
+// same name in overrides replacing them and new ones added after them
+func MergeArgumentDefs(base, overrides []ArgumentDef) []ArgumentDef {
+	if len(overrides) == 0 {
+		return base
+	}
+
+	merged := make([]ArgumentDef, 0, len(base)+len(overrides))
+	merged = append(merged, base...)
+	for _, def := range overrides {
+		replaced := false
+		for i := range merged {
+			if merged[i].Name == def.Name {
+				merged[i] = def
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, def)
+		}
+	}
+	return merged
+}
+
+// Override returns the definition with the fields set in other replacing its
+// own, so a step can change the prompt, default, required or secret flag,
+// environment variable, source or validation of a shared argument
+func (a ArgumentDef) Override(other ArgumentDef) ArgumentDef {
+	if other.Prompt != "" {
+		a.Prompt = other.Prompt
+	}
+	if other.Default != "" || other.DefaultFrom != "" {
+		a.Default = other.Default
+		a.DefaultFrom = other.DefaultFrom
+	}
+	if other.Required {
+		a.Required = true
+	}
+	if other.Env != "" {
+		a.Env = other.Env
+	}
+	if other.Secret {
+		a.Secret = true
+	}
+	if other.From != nil {
+		a.From = other.From
+	}
+	if other.Type != "" {
+		a.Type = other.Type
+	}
+	if len(other.Choices) > 0 {
+		a.Choices = other.Choices
+	}
+	if other.Pattern != "" {
+		a.Pattern = other.Pattern
+	}
+	if other.Min != nil {
+		a.Min = other.Min
+	}
+	if other.Max != nil {
+		a.Max = other.Max
+	}
+	if other.Error != "" {
+		a.Error = other.Error
+	}
+	return a
+}
+
+// HasComputedDefault reports whether the argument's default is computed when
+// the run starts, from other arguments or by a shell snippet
+func (a ArgumentDef) HasComputedDefault() bool {
+	return a.DefaultFrom != "" || strings.Contains(a.Default, "{{")
+}
+
+// IsSecret reports whether the argument's value must not be shown. Values
+// fetched from a source are always secret.
+func (a ArgumentDef) IsSecret() bool {
+	return a.Secret || a.From != nil
+}
+
+// Argument types
+const (
+	ArgTypeString = "string"
+	ArgTypeInt    = "int"
+	ArgTypeBool   = "bool"
+	ArgTypeEnum   = "enum"
+	ArgTypePath   = "path"
+	ArgTypePort   = "port"
+	ArgTypeList   = "list" // Comma-separated values, can be looped over in templates
+)
+
+// Validate checks a value against the argument's type, choices, pattern and
+// range. Empty values are not checked, required arguments are checked separately.
+func (a ArgumentDef) Validate(value string) error {
+	if value == "" {
+		return nil
+	}
+	if reason := a.invalidReason(value); reason != "" {
+		if a.Error != "" {
+			reason = a.Error
+		}
+		if a.IsSecret() {
+			// Secret values are not repeated in messages
+			return fmt.Errorf("invalid value for %s: %s", a.Name, reason)
+		}
+		return fmt.Errorf("invalid value %q for %s: %s", value, a.Name, reason)
+	}
+	return nil
+}
+
+// invalidReason returns why a value is invalid, or "" if it is valid
+func (a ArgumentDef) invalidReason(value string) string {
+	switch a.Type {
+	case "", ArgTypeString:
+	case ArgTypeInt, ArgTypePort:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return "must be a whole number"
+		}
+		if a.Type == ArgTypePort && (n < 1 || n > 65535) {
+			return "must be a port number between 1 and 65535"
+		}
+		if a.Min != nil && n < *a.Min {
+			return fmt.Sprintf("must be at least %d", *a.Min)
+		}
+		if a.Max != nil && n > *a.Max {
+			return fmt.Sprintf("must be at most %d", *a.Max)
+		}
+	case ArgTypeBool:
+		switch strings.ToLower(value) {
+		case "true", "false", "yes", "no", "y", "n", "1", "0":
+		default:
+			return "must be true or false"
+		}
+	case ArgTypeEnum:
+		if len(a.Choices) == 0 {
+			return "enum argument has no choices"
+		}
+	case ArgTypeList:
+		// Choices and pattern apply to each item
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			if len(a.Choices) > 0 {
+				if reason := a.choiceReason(item); reason != "" {
+					return reason
+				}
+			}
+			if reason := a.patternReason(item); reason != "" {
+				return reason
+			}
+		}
+		return ""
+	case ArgTypePath:
+		if strings.ContainsAny(value, "\x00\n") {
+			return "must be a path"
+		}
+	default:
+		return fmt.Sprintf("unknown argument type '%s'", a.Type)
+	}
+
+	if len(a.Choices) > 0 {
+		if reason := a.choiceReason(value); reason != "" {
+			return reason
+		}
+	}
+	return a.patternReason(value)
+}
+
+// choiceReason returns why a value is not one of the choices, or "" if it is
+func (a ArgumentDef) choiceReason(value string) string {
+	for _, choice := range a.Choices {
+		if value == choice {
+			return ""
+		}
+	}
+	return fmt.Sprintf("must be one of: %s", strings.Join(a.Choices, ", "))
+}
+
+// patternReason returns why a value does not match the pattern, or "" if it does
+func (a ArgumentDef) patternReason(value string) string {
+	if a.Pattern != "" {
+		pattern, err := regexp.Compile(`^(?:` + a.Pattern + `)$`)
+		if err != nil {
+			return fmt.Sprintf("invalid pattern '%s': %v", a.Pattern, err)
+		}
+		if !pattern.MatchString(value) {
+			return fmt.Sprintf("must match %s", a.Pattern)
+		}
+	}
+	return ""
+}
+
+// Validate checks that exactly one source is set
+func (s ArgumentSource) Validate() error {
+	set := 0
+	for _, source := range []string{s.File, s.Command, s.Env} {
+		if source != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("from must set exactly one of file, command or env")
+	}
+	return nil
+}
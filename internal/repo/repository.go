@@ -14,17 +14,56 @@ import (
 type ArgumentDef struct {
 	Name     string `yaml:"name"`               // Variable name (e.g., "username")
 	Prompt   string `yaml:"prompt,omitempty"`   // Prompt question (e.g., "Enter your name:")
-	Default  string `yaml:"default,omitempty"`  // Default value
+	Default  string `yaml:"default,omitempty"`  // Default value, may refer to other arguments, e.g. "{{size | mib}}"
 	Required bool   `yaml:"required,omitempty"` // Whether argument is required
+	Env      string `yaml:"env,omitempty"`      // Environment variable whose value, if set, replaces the default
+	Secret   bool   `yaml:"secret,omitempty"`   // Value is read without echo, passed to commands through the environment and never shown
+
+	DefaultFrom string          `yaml:"default_from,omitempty"` // Shell snippet whose output is the default, e.g. "nproc"
+	From        *ArgumentSource `yaml:"from,omitempty"`         // Where the value is fetched from before the run; such values are secret
+
+	Type    string   `yaml:"type,omitempty"`    // "string" (default), "int", "bool", "enum", "path", "port" or "list"
+	Choices []string `yaml:"choices,omitempty"` // Allowed values, offered as a menu when prompting
+	Pattern string   `yaml:"pattern,omitempty"` // Regular expression the whole value must match
+	Min     *int     `yaml:"min,omitempty"`     // Minimum value of an int or port
+	Max     *int     `yaml:"max,omitempty"`     // Maximum value of an int or port
+	Error   string   `yaml:"error,omitempty"`   // Message shown instead of the generated one when a value is invalid
+}
+
+// ArgumentSource is where an argument's value is fetched from, e.g. a file
+// kept by a secrets tool or a password manager's CLI. Exactly one is set.
+type ArgumentSource struct {
+	File    string `yaml:"file,omitempty"`    // File holding the value, ~ is expanded
+	Command string `yaml:"command,omitempty"` // Shell command printing the value
+	Env     string `yaml:"env,omitempty"`     // Environment variable holding the value
 }
 
 // Command represents a single command step
 type Command struct {
+	ID          string            `yaml:"id,omitempty"` // Optional step id for depends_on and step selection (e.g., "install")
+	Tags        []string          `yaml:"tags,omitempty"` // Optional labels for selecting several steps at once (e.g., --only tag:verify)
 	Description string            `yaml:"description"`
 	Command     string            `yaml:"command,omitempty"`   // Single command (backward compatibility)
 	Platforms   map[string]string `yaml:"platforms,omitempty"` // Platform-specific commands: platform -> command
+	Script      string            `yaml:"script,omitempty"`      // Multi-line script run from a file instead of command (e.g., for if/else logic)
+	Interpreter string            `yaml:"interpreter,omitempty"` // Program running the script (e.g., "bash", "python3" or "#!/usr/bin/env node"; default: its shebang, else the shell)
+	Shell       string            `yaml:"shell,omitempty"`         // Shell running the step's commands (e.g., "bash"; default: the set's shell)
+	ShellOptions []string         `yaml:"shell_options,omitempty"` // Options the shell is started with (e.g., errexit, pipefail; default: the set's options)
 	SkipOnError bool              `yaml:"skip_on_error,omitempty"`
 	Args        []ArgumentDef     `yaml:"args,omitempty"` // Argument definitions for this command
+	DependsOn   []string          `yaml:"depends_on,omitempty"` // Step ids or numbers that must finish first; steps run concurrently when set
+
+	Check          string            `yaml:"check,omitempty"`           // Command that exits 0 when the step is already satisfied
+	CheckPlatforms map[string]string `yaml:"check_platforms,omitempty"` // Platform-specific checks: platform -> check
+	When           string            `yaml:"when,omitempty"`            // Condition for running the step (e.g., "args.ssl == 'yes'")
+	Register       string            `yaml:"register,omitempty"`        // Variable that captures the step's trimmed stdout for later steps
+	Undo           string            `yaml:"undo,omitempty"`            // Command that reverts the step when a later step fails
+	UndoPlatforms  map[string]string `yaml:"undo_platforms,omitempty"`  // Platform-specific undo commands: platform -> undo
+
+	Timeout      string `yaml:"timeout,omitempty"`       // Maximum duration of one attempt (e.g., "30s", "5m")
+	Retries      int    `yaml:"retries,omitempty"`       // Number of times to retry after a failed attempt
+	RetryDelay   string `yaml:"retry_delay,omitempty"`   // Delay between attempts (e.g., "10s")
+	RetryBackoff string `yaml:"retry_backoff,omitempty"` // "fixed" (default) or "exponential" (delay doubles each retry)
 }
 
 // CommandSet represents a collection of commands for a topic
@@ -32,7 +71,12 @@ type CommandSet struct {
 	Name        string    `yaml:"name"`
 	Description string    `yaml:"description"`
 	Version     string    `yaml:"version"`
-	Commands    []Command `yaml:"commands"`
+	Session     string        `yaml:"session,omitempty"` // "isolated" (default) or "persistent" (all steps share one shell)
+	Args        []ArgumentDef `yaml:"args,omitempty"`    // Arguments shared by all steps, steps can override them
+	QuoteArgs   *bool         `yaml:"quote_args,omitempty"` // Shell-quote substituted values (default: false, new sets set it to true)
+	Shell       string        `yaml:"shell,omitempty"`      // Shell running the commands (default: from config, else sh)
+	ShellOptions []string     `yaml:"shell_options,omitempty"` // Options the shell is started with (e.g., errexit, pipefail)
+	Commands    []Command     `yaml:"commands"`
 }
 
 // VersionInfo represents a single version of a command set
@@ -41,13 +85,23 @@ type VersionInfo struct {
 	Tag         string    `yaml:"tag,omitempty"`    // Optional tag for this version (e.g., "certonly", "nginx")
 	Description string    `yaml:"description"`
 	Latest      bool      `yaml:"latest,omitempty"` // Mark this version as latest
-	Commands    []Command `yaml:"commands"`
+	Session     string        `yaml:"session,omitempty"`
+	Args        []ArgumentDef `yaml:"args,omitempty"`
+	QuoteArgs   *bool         `yaml:"quote_args,omitempty"`
+	Shell       string        `yaml:"shell,omitempty"`
+	ShellOptions []string     `yaml:"shell_options,omitempty"`
+	Commands    []Command     `yaml:"commands"`
 }
 
 // VersionedCommandSet represents a command set with multiple versions
 type VersionedCommandSet struct {
 	Name        string        `yaml:"name"`
 	Description string        `yaml:"description,omitempty"`
+	Session     string        `yaml:"session,omitempty"` // Default session mode for versions that don't set one
+	Args        []ArgumentDef `yaml:"args,omitempty"`    // Arguments shared by all versions, versions can redefine them
+	QuoteArgs   *bool         `yaml:"quote_args,omitempty"` // Default quoting for versions that don't set it
+	Shell       string        `yaml:"shell,omitempty"`      // Default shell for versions that don't set one
+	ShellOptions []string     `yaml:"shell_options,omitempty"` // Default shell options for versions that don't set them
 	Versions    []VersionInfo `yaml:"versions"` // Array of versions
 }
 
@@ -139,11 +193,33 @@ func (r *Repository) GetCommandSet(name string, version string) (*CommandSet, er
 			return nil, fmt.Errorf("command set '%s' version or tag '%s' not found", name, version)
 		}
 
+		session := foundVersion.Session
+		if session == "" {
+			session = versionedCmdSet.Session
+		}
+		quoteArgs := foundVersion.QuoteArgs
+		if quoteArgs == nil {
+			quoteArgs = versionedCmdSet.QuoteArgs
+		}
+		// Options go with the shell they are given for
+		shell, shellOptions := foundVersion.Shell, foundVersion.ShellOptions
+		if shell == "" {
+			shell = versionedCmdSet.Shell
+			if shellOptions == nil {
+				shellOptions = versionedCmdSet.ShellOptions
+			}
+		}
+
 		// Convert VersionInfo to CommandSet
 		cmdSet := CommandSet{
 			Name:        versionedCmdSet.Name,
 			Description: foundVersion.Description,
 			Version:     foundVersion.Version,
+			Session:     session,
+			Args:        MergeArgumentDefs(versionedCmdSet.Args, foundVersion.Args),
+			QuoteArgs:   quoteArgs,
+			Shell:       shell,
+			ShellOptions: shellOptions,
 			Commands:    foundVersion.Commands,
 		}
 
@@ -276,6 +352,11 @@ func (r *Repository) SaveCommandSet(cmdSet *CommandSet, version string) error {
 				if v == versionToSave || strings.TrimPrefix(v, "v") == strings.TrimPrefix(versionToSave, "v") {
 					// Update existing version
 					versionedCmdSet.Versions[i].Description = cmdSet.Description
+					versionedCmdSet.Versions[i].Session = cmdSet.Session
+					versionedCmdSet.Versions[i].Args = cmdSet.Args
+					versionedCmdSet.Versions[i].QuoteArgs = cmdSet.QuoteArgs
+					versionedCmdSet.Versions[i].Shell = cmdSet.Shell
+					versionedCmdSet.Versions[i].ShellOptions = cmdSet.ShellOptions
 					versionedCmdSet.Versions[i].Commands = cmdSet.Commands
 					versionExists = true
 					break
@@ -287,6 +368,11 @@ func (r *Repository) SaveCommandSet(cmdSet *CommandSet, version string) error {
 				versionedCmdSet.Versions = append(versionedCmdSet.Versions, VersionInfo{
 					Version:     versionToSave,
 					Description: cmdSet.Description,
+					Session:     cmdSet.Session,
+					Args:        cmdSet.Args,
+					QuoteArgs:   cmdSet.QuoteArgs,
+					Shell:       cmdSet.Shell,
+					ShellOptions: cmdSet.ShellOptions,
 					Commands:    cmdSet.Commands,
 					Latest:      false, // Will be set below if needed
 				})
@@ -336,12 +422,22 @@ func (r *Repository) SaveCommandSet(cmdSet *CommandSet, version string) error {
 					{
 						Version:     oldVersion,
 						Description: oldCmdSet.Description,
+						Session:     oldCmdSet.Session,
+						Args:        oldCmdSet.Args,
+						QuoteArgs:   oldCmdSet.QuoteArgs,
+						Shell:       oldCmdSet.Shell,
+						ShellOptions: oldCmdSet.ShellOptions,
 						Commands:    oldCmdSet.Commands,
 						Latest:      oldVersionNum >= newVersionNum,
 					},
 					{
 						Version:     versionToSave,
 						Description: cmdSet.Description,
+						Session:     cmdSet.Session,
+						Args:        cmdSet.Args,
+						QuoteArgs:   cmdSet.QuoteArgs,
+						Shell:       cmdSet.Shell,
+						ShellOptions: cmdSet.ShellOptions,
 						Commands:    cmdSet.Commands,
 						Latest:      newVersionNum > oldVersionNum,
 					},
@@ -353,6 +449,11 @@ func (r *Repository) SaveCommandSet(cmdSet *CommandSet, version string) error {
 					{
 						Version:     versionToSave,
 						Description: cmdSet.Description,
+						Session:     cmdSet.Session,
+						Args:        cmdSet.Args,
+						QuoteArgs:   cmdSet.QuoteArgs,
+						Shell:       cmdSet.Shell,
+						ShellOptions: cmdSet.ShellOptions,
 						Commands:    cmdSet.Commands,
 						Latest:      true,
 					},
@@ -366,6 +467,11 @@ func (r *Repository) SaveCommandSet(cmdSet *CommandSet, version string) error {
 			{
 				Version:     versionToSave,
 				Description: cmdSet.Description,
+				Session:     cmdSet.Session,
+				Args:        cmdSet.Args,
+				QuoteArgs:   cmdSet.QuoteArgs,
+				Shell:       cmdSet.Shell,
+				ShellOptions: cmdSet.ShellOptions,
 				Commands:    cmdSet.Commands,
 				Latest:      true,
 			},
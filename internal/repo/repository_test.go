@@ -3,6 +3,7 @@ package repo
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -157,6 +158,270 @@ commands:
 	}
 }
 
+func TestGetCommandSet_Session(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := NewRepository(tmpDir)
+
+	yamlContent := `name: test
+session: persistent
+versions:
+  - version: "v1"
+    description: Inherits the session
+    commands:
+      - description: Test command
+        command: cd /tmp
+  - version: "v2"
+    description: Overrides the session
+    session: isolated
+    commands:
+      - description: Test command
+        command: cd /tmp
+`
+	filePath := filepath.Join(tmpDir, "test.yaml")
+	err := os.WriteFile(filePath, []byte(yamlContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	cmdSet, err := repo.GetCommandSet("test", "v1")
+	if err != nil {
+		t.Fatalf("GetCommandSet failed: %v", err)
+	}
+	if cmdSet.Session != "persistent" {
+		t.Errorf("Expected session 'persistent', got %q", cmdSet.Session)
+	}
+
+	cmdSet, err = repo.GetCommandSet("test", "v2")
+	if err != nil {
+		t.Fatalf("GetCommandSet failed: %v", err)
+	}
+	if cmdSet.Session != "isolated" {
+		t.Errorf("Expected session 'isolated', got %q", cmdSet.Session)
+	}
+}
+
+func TestGetCommandSet_Shell(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := NewRepository(tmpDir)
+
+	yamlContent := `name: test
+shell: bash
+shell_options: [pipefail]
+versions:
+  - version: "v1"
+    description: Inherits the shell
+    commands:
+      - description: Test command
+        command: echo hi
+  - version: "v2"
+    description: Names its own shell
+    shell: zsh
+    commands:
+      - description: Test command
+        command: echo hi
+  - version: "v3"
+    description: Only sets options
+    shell_options: [errexit]
+    commands:
+      - description: Test command
+        command: echo hi
+`
+	filePath := filepath.Join(tmpDir, "test.yaml")
+	if err := os.WriteFile(filePath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	tests := []struct {
+		version string
+		shell   string
+		options []string
+	}{
+		{"v1", "bash", []string{"pipefail"}},
+		{"v2", "zsh", nil},
+		{"v3", "bash", []string{"errexit"}},
+	}
+	for _, tt := range tests {
+		cmdSet, err := repo.GetCommandSet("test", tt.version)
+		if err != nil {
+			t.Fatalf("GetCommandSet(%s) failed: %v", tt.version, err)
+		}
+		if cmdSet.Shell != tt.shell || strings.Join(cmdSet.ShellOptions, ",") != strings.Join(tt.options, ",") {
+			t.Errorf("%s: expected shell %q with %v, got %q with %v", tt.version, tt.shell, tt.options, cmdSet.Shell, cmdSet.ShellOptions)
+		}
+	}
+}
+
+func TestGetCommandSet_SharedArgs(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := NewRepository(tmpDir)
+
+	yamlContent := `name: test
+args:
+  - name: size
+    prompt: "Enter size"
+    default: "1G"
+  - name: path
+    default: /swapfile
+versions:
+  - version: "v1"
+    description: Inherits the args
+    commands:
+      - description: Test command
+        command: echo {{size}}
+  - version: "v2"
+    description: Overrides an arg
+    args:
+      - name: size
+        default: "2G"
+      - name: user
+        required: true
+    commands:
+      - description: Test command
+        command: echo {{size}}
+`
+	filePath := filepath.Join(tmpDir, "test.yaml")
+	err := os.WriteFile(filePath, []byte(yamlContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	cmdSet, err := repo.GetCommandSet("test", "v1")
+	if err != nil {
+		t.Fatalf("GetCommandSet failed: %v", err)
+	}
+	if len(cmdSet.Args) != 2 || cmdSet.Args[0].Default != "1G" {
+		t.Errorf("Expected the set's args, got %+v", cmdSet.Args)
+	}
+
+	cmdSet, err = repo.GetCommandSet("test", "v2")
+	if err != nil {
+		t.Fatalf("GetCommandSet failed: %v", err)
+	}
+	if len(cmdSet.Args) != 3 {
+		t.Fatalf("Expected 3 args, got %+v", cmdSet.Args)
+	}
+	if cmdSet.Args[0].Name != "size" || cmdSet.Args[0].Default != "2G" {
+		t.Errorf("Expected the version to override 'size', got %+v", cmdSet.Args[0])
+	}
+	if cmdSet.Args[1].Name != "path" || cmdSet.Args[2].Name != "user" {
+		t.Errorf("Expected 'path' then 'user', got %+v", cmdSet.Args)
+	}
+}
+
+func TestArgumentDefOverride(t *testing.T) {
+	shared := ArgumentDef{Name: "size", Prompt: "Enter size", Default: "1G"}
+
+	overridden := shared.Override(ArgumentDef{Name: "size", Default: "2G", Required: true, Env: "SWAP_SIZE", Secret: true})
+	if overridden.Prompt != "Enter size" || overridden.Default != "2G" || !overridden.Required || overridden.Env != "SWAP_SIZE" || !overridden.Secret {
+		t.Errorf("Unexpected override: %+v", overridden)
+	}
+
+	if unchanged := shared.Override(ArgumentDef{Name: "size"}); unchanged.Default != "1G" || unchanged.Required {
+		t.Errorf("Expected an empty override to keep the definition, got %+v", unchanged)
+	}
+
+	// A computed default replaces both parts of the shared default
+	computed := ArgumentDef{Name: "jobs", Default: "1", DefaultFrom: "nproc"}
+	if overridden := computed.Override(ArgumentDef{Name: "jobs", Default: "4"}); overridden.Default != "4" || overridden.DefaultFrom != "" {
+		t.Errorf("Expected the static default to replace the computed one, got %+v", overridden)
+	}
+}
+
+func TestArgumentDefHasComputedDefault(t *testing.T) {
+	tests := []struct {
+		def      ArgumentDef
+		expected bool
+	}{
+		{ArgumentDef{Name: "size", Default: "2G"}, false},
+		{ArgumentDef{Name: "size"}, false},
+		{ArgumentDef{Name: "count", Default: "{{size | mib}}"}, true},
+		{ArgumentDef{Name: "jobs", DefaultFrom: "nproc"}, true},
+	}
+
+	for _, tt := range tests {
+		if result := tt.def.HasComputedDefault(); result != tt.expected {
+			t.Errorf("HasComputedDefault(%+v) = %v, expected %v", tt.def, result, tt.expected)
+		}
+	}
+}
+
+func TestArgumentDefValidate(t *testing.T) {
+	zero, hundred := 0, 100
+	tests := []struct {
+		def   ArgumentDef
+		value string
+		valid bool
+	}{
+		{ArgumentDef{Name: "name"}, "anything", true},
+		{ArgumentDef{Name: "swappiness", Type: "int", Min: &zero, Max: &hundred}, "60", true},
+		{ArgumentDef{Name: "swappiness", Type: "int", Min: &zero, Max: &hundred}, "0", true},
+		{ArgumentDef{Name: "swappiness", Type: "int", Min: &zero, Max: &hundred}, "banana", false},
+		{ArgumentDef{Name: "swappiness", Type: "int", Min: &zero, Max: &hundred}, "101", false},
+		{ArgumentDef{Name: "swappiness", Type: "int", Min: &zero, Max: &hundred}, "-1", false},
+		{ArgumentDef{Name: "ssl", Type: "bool"}, "yes", true},
+		{ArgumentDef{Name: "ssl", Type: "bool"}, "FALSE", true},
+		{ArgumentDef{Name: "ssl", Type: "bool"}, "maybe", false},
+		{ArgumentDef{Name: "mode", Type: "enum", Choices: []string{"dev", "prod"}}, "prod", true},
+		{ArgumentDef{Name: "mode", Type: "enum", Choices: []string{"dev", "prod"}}, "test", false},
+		{ArgumentDef{Name: "mode", Type: "enum"}, "dev", false},
+		{ArgumentDef{Name: "port", Type: "port"}, "8080", true},
+		{ArgumentDef{Name: "port", Type: "port"}, "0", false},
+		{ArgumentDef{Name: "port", Type: "port"}, "70000", false},
+		{ArgumentDef{Name: "dir", Type: "path"}, "/var/lib/app", true},
+		{ArgumentDef{Name: "dir", Type: "path"}, "/var\n/lib", false},
+		{ArgumentDef{Name: "size", Pattern: `[0-9]+[KMG]`}, "2G", true},
+		{ArgumentDef{Name: "size", Pattern: `[0-9]+[KMG]`}, "2G; rm -rf /", false},
+		{ArgumentDef{Name: "size", Pattern: `[`}, "2G", false},
+		{ArgumentDef{Name: "tools", Type: "list", Choices: []string{"git", "curl"}}, "git, curl", true},
+		{ArgumentDef{Name: "tools", Type: "list", Choices: []string{"git", "curl"}}, "git,wget", false},
+		{ArgumentDef{Name: "ports", Type: "list", Pattern: `[0-9]+`}, "80,443", true},
+		{ArgumentDef{Name: "x", Type: "float"}, "1.5", false},
+		{ArgumentDef{Name: "swappiness", Type: "int", Required: true}, "", true},
+	}
+
+	for _, tt := range tests {
+		err := tt.def.Validate(tt.value)
+		if (err == nil) != tt.valid {
+			t.Errorf("Validate(%q) for %+v: expected valid=%v, got %v", tt.value, tt.def, tt.valid, err)
+		}
+	}
+
+	// A custom error message replaces the generated one
+	def := ArgumentDef{Name: "size", Pattern: `[0-9]+[KMG]`, Error: "use a size like 2G"}
+	if err := def.Validate("big"); err == nil || err.Error() != `invalid value "big" for size: use a size like 2G` {
+		t.Errorf("Expected the custom error message, got %v", err)
+	}
+
+	// Secret values are left out of the message
+	secret := ArgumentDef{Name: "pin", Type: "int", Secret: true}
+	if err := secret.Validate("hunter2"); err == nil || strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("Expected an error without the secret value, got %v", err)
+	}
+}
+
+func TestArgumentSourceValidate(t *testing.T) {
+	tests := []struct {
+		source ArgumentSource
+		valid  bool
+	}{
+		{ArgumentSource{File: "~/.secrets/db"}, true},
+		{ArgumentSource{Command: "pass show db/prod"}, true},
+		{ArgumentSource{Env: "DB_PASS"}, true},
+		{ArgumentSource{}, false},
+		{ArgumentSource{File: "~/.secrets/db", Env: "DB_PASS"}, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.source.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate() for %+v: expected valid=%v, got %v", tt.source, tt.valid, err)
+		}
+	}
+
+	if def := (ArgumentDef{Name: "db_pass", From: &ArgumentSource{Env: "DB_PASS"}}); !def.IsSecret() {
+		t.Error("Expected an argument with a source to be secret")
+	}
+}
+
 func TestListVersions(t *testing.T) {
 	tmpDir := t.TempDir()
 	repo := NewRepository(tmpDir)
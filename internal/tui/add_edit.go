@@ -22,10 +22,13 @@ type addEditModel struct {
 
 func newAddEditModel(manager *repo.Manager, isEdit bool, cmdSet *repo.CommandSet) *addEditModel {
 	if cmdSet == nil {
+		// New sets shell-quote argument values
+		quoteArgs := true
 		cmdSet = &repo.CommandSet{
 			Name:        "",
 			Description: "",
 			Version:     "v1",
+			QuoteArgs:   &quoteArgs,
 			Commands:    []repo.Command{},
 		}
 	}
@@ -131,7 +134,7 @@ func (m *addEditModel) save() (*addEditModel, tea.Cmd) {
 	// Remove empty commands
 	var validCommands []repo.Command
 	for _, cmd := range m.cmdSet.Commands {
-		if cmd.Description != "" && cmd.Command != "" {
+		if cmd.Description != "" && (cmd.Command != "" || cmd.Script != "") {
 			validCommands = append(validCommands, cmd)
 		}
 	}